@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reservationRow returns the table row of a reservation listed by list, show and reserve.
+func reservationRow(r connector.Reservation) []string {
+	labels := make([]string, 0, len(r.Labels))
+	for key, value := range r.Labels {
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	return []string{r.NetmaskId, r.Cidr, r.Owner, r.Description, strings.Join(labels, ","), r.ExpiresAt, strconv.FormatBool(r.ChildPool)}
+}
+
+var reservationHeader = []string{"NETMASK_ID", "CIDR", "OWNER", "DESCRIPTION", "LABELS", "EXPIRES_AT", "CHILD_POOL"}
+
+// labelsFlag collects repeated -label key=value flags.
+type labelsFlag map[string]string
+
+func (labels labelsFlag) String() string {
+	return fmt.Sprint(map[string]string(labels))
+}
+
+func (labels labelsFlag) Set(value string) error {
+	key, labelValue, found := strings.Cut(value, "=")
+	if !found {
+		return fmt.Errorf("The label %s does not match the format key=value!", value)
+	}
+	labels[key] = labelValue
+	return nil
+}
+
+// parse parses the flags of a command and returns its arguments, which have to match names.
+func (c *cli) parse(flags *flag.FlagSet, args []string, names ...string) ([]string, error) {
+	flags.SetOutput(c.stderr)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() != len(names) {
+		return nil, fmt.Errorf("%s expects the arguments %s, got %v!", flags.Name(), strings.Join(names, " "), flags.Args())
+	}
+	for i, name := range names {
+		if name == "<base_cidr>" {
+			if err := verifyBaseCidr(flags.Arg(i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return flags.Args(), nil
+}
+
+// verifyBaseCidr rejects invalid base cidr ranges as well as those with host bits set, which would name another
+// reservation document than the canonical form of the same range, e.g. 10.116.1.0/14 instead of 10.116.0.0/14.
+func verifyBaseCidr(baseCidr string) error {
+	_, ipNet, err := net.ParseCIDR(baseCidr)
+	if err != nil {
+		return fmt.Errorf("The base cidr %s is invalid: %s", baseCidr, err)
+	}
+	if ipNet.String() != baseCidr {
+		return fmt.Errorf("The base cidr %s has host bits set, use %s instead!", baseCidr, ipNet)
+	}
+	return nil
+}
+
+// read returns the reservation document of baseCidr, or an empty one if there is none yet.
+func (c *cli) read(ctx context.Context, baseCidr string) (*connector.NetworkConfig, error) {
+	networkConfig, err := c.newConnector(baseCidr).ReadRemote(ctx)
+	if errors.Is(err, connector.ErrNotExist) {
+		return &connector.NetworkConfig{Subnets: make(map[string]string)}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the reservations of %s: %s", baseCidr, err)
+	}
+	return networkConfig, nil
+}
+
+// update runs connector.Update and retries it on concurrent modifications.
+func (c *cli) update(ctx context.Context, baseCidr string, modify func(networkConfig *connector.NetworkConfig) error) error {
+	return connector.Retry(func() error {
+		return connector.Update(ctx, c.newConnector(baseCidr), modify)
+	}, func(err error) bool {
+		return errors.Is(err, connector.ErrConflict)
+	})
+}
+
+// errInvalid, errNotFound, errExists and errUnsatisfiable classify the errors of requests, e.g. for the HTTP status of the server.
+var (
+	errInvalid       = errors.New("invalid request")
+	errNotFound      = errors.New("not found")
+	errExists        = errors.New("already exists")
+	errUnsatisfiable = errors.New("cannot be reserved")
+)
+
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify returns an error formatted like fmt.Errorf, which matches class with errors.Is.
+func classify(class error, format string, args ...interface{}) error {
+	return classifiedError{class, fmt.Errorf(format, args...)}
+}
+
+// reservations returns the reservations of baseCidr ordered by their cidr ranges.
+func (c *cli) reservations(ctx context.Context, baseCidr string) ([]connector.Reservation, error) {
+	networkConfig, err := c.read(ctx, baseCidr)
+	if err != nil {
+		return nil, err
+	}
+	reservations := make([]connector.Reservation, 0, len(networkConfig.Subnets))
+	for netmaskId := range networkConfig.Subnets {
+		reservations = append(reservations, networkConfig.Reservation(netmaskId))
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return lessCidr(reservations[i].Cidr, reservations[j].Cidr)
+	})
+	return reservations, nil
+}
+
+// reserve reserves a cidr range within baseCidr as described by request.
+func (c *cli) reserve(ctx context.Context, baseCidr string, request connector.ReservationRequest) (connector.Reservation, error) {
+	if request.NetmaskId == "" {
+		return connector.Reservation{}, classify(errInvalid, "The netmask_id must not be empty!")
+	}
+	if (request.PrefixLength == 0) == (request.RequestedCidr == "") {
+		return connector.Reservation{}, classify(errInvalid, "Either a prefix length or a cidr has to be given!")
+	}
+	if _, err := cidrCalculator.ParseStrategy(string(request.Strategy)); err != nil {
+		return connector.Reservation{}, classifiedError{errInvalid, err}
+	}
+	if request.LeaseDuration != "" {
+		if _, err := connector.NewLease(request.LeaseDuration); err != nil {
+			return connector.Reservation{}, classifiedError{errInvalid, err}
+		}
+	}
+	if reserver, ok := c.newConnector(baseCidr).(connector.Reserver); ok {
+		return reserver.Reserve(ctx, request)
+	}
+	var result connector.Reservation
+	err := c.update(ctx, baseCidr, func(networkConfig *connector.NetworkConfig) error {
+		_, err := networkConfig.Reserve(ctx, c.newConnector, baseCidr, request)
+		if errors.Is(err, connector.ErrNetmaskIdExists) {
+			return classify(errExists, "The netmaskId %s already exists!", request.NetmaskId)
+		}
+		if err != nil {
+			return classifiedError{errUnsatisfiable, err}
+		}
+		result = networkConfig.Reservation(request.NetmaskId)
+		return nil
+	})
+	return result, err
+}
+
+// release releases the reservation netmaskId of baseCidr and returns it.
+func (c *cli) release(ctx context.Context, baseCidr string, netmaskId string) (connector.Reservation, error) {
+	if reserver, ok := c.newConnector(baseCidr).(connector.Reserver); ok {
+		released, err := c.read(ctx, baseCidr)
+		if err != nil {
+			return connector.Reservation{}, err
+		}
+		if _, contains := released.Subnets[netmaskId]; !contains {
+			return connector.Reservation{}, classify(errNotFound, "Netmask with id %s does not exist in %s!", netmaskId, baseCidr)
+		}
+		return released.Reservation(netmaskId), reserver.Release(ctx, netmaskId)
+	}
+	var released connector.Reservation
+	err := c.update(ctx, baseCidr, func(networkConfig *connector.NetworkConfig) error {
+		if _, contains := networkConfig.Subnets[netmaskId]; !contains {
+			return classify(errNotFound, "Netmask with id %s does not exist in %s!", netmaskId, baseCidr)
+		}
+		if networkConfig.ChildPools[netmaskId] != "" {
+			return classify(errInvalid, "The netmaskId %s has been promoted into a child pool, which has to be released by its network request!", netmaskId)
+		}
+		released = networkConfig.Reservation(netmaskId)
+		networkConfig.Release(netmaskId)
+		return nil
+	})
+	return released, err
+}
+
+func pools(ctx context.Context, c *cli, args []string) error {
+	if _, err := c.parse(flag.NewFlagSet("pools", flag.ContinueOnError), args); err != nil {
+		return err
+	}
+	// any connector lists the whole storage location, regardless of its base cidr range
+	baseCidrs, err := connector.ListBaseCidrs(ctx, c.newConnector("0.0.0.0/0"))
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(baseCidrs))
+	for _, baseCidr := range baseCidrs {
+		rows = append(rows, []string{baseCidr})
+	}
+	return c.write(baseCidrs, []string{"BASE_CIDR"}, rows)
+}
+
+func list(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(flag.NewFlagSet("list", flag.ContinueOnError), args, "<base_cidr>")
+	if err != nil {
+		return err
+	}
+	reservations, err := c.reservations(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(reservations))
+	for _, r := range reservations {
+		rows = append(rows, reservationRow(r))
+	}
+	return c.write(reservations, reservationHeader, rows)
+}
+
+func show(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(flag.NewFlagSet("show", flag.ContinueOnError), args, "<base_cidr>", "<netmask_id>")
+	if err != nil {
+		return err
+	}
+	baseCidr, netmaskId := args[0], args[1]
+	networkConfig, err := c.read(ctx, baseCidr)
+	if err != nil {
+		return err
+	}
+	if _, contains := networkConfig.Subnets[netmaskId]; !contains {
+		return fmt.Errorf("Netmask with id %s does not exist in %s!", netmaskId, baseCidr)
+	}
+	events := connector.FilterHistory(networkConfig.History, netmaskId, nil)
+	if c.output == "json" {
+		return c.write(map[string]interface{}{"reservation": networkConfig.Reservation(netmaskId), "history": events}, nil, nil)
+	}
+	if err := c.write(nil, reservationHeader, [][]string{reservationRow(networkConfig.Reservation(netmaskId))}); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout)
+	return writeHistory(c, events)
+}
+
+func reserve(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("reserve", flag.ContinueOnError)
+	prefixLength := flags.Int("prefix-length", 0, "prefix length of the cidr range to reserve")
+	requestedCidr := flags.String("cidr", "", "reserves exactly this cidr range instead of the next free one")
+	strategy := flags.String("strategy", "", "allocation strategy, one of first_fit, best_fit and append")
+	owner := flags.String("owner", "", "owner of the reservation")
+	description := flags.String("description", "", "why the cidr range is reserved")
+	leaseDuration := flags.String("lease", "", "lifetime of the reservation, e.g. 168h")
+	labels := labelsFlag{}
+	flags.Var(labels, "label", "label of the reservation as key=value, may be repeated")
+	args, err := c.parse(flags, args, "<base_cidr>", "<netmask_id>")
+	if err != nil {
+		return err
+	}
+	result, err := c.reserve(ctx, args[0], connector.ReservationRequest{
+		NetmaskId:     args[1],
+		PrefixLength:  *prefixLength,
+		RequestedCidr: *requestedCidr,
+		Strategy:      cidrCalculator.Strategy(*strategy),
+		Metadata:      connector.ReservationMetadata{Description: *description, Owner: *owner, Labels: labels},
+		LeaseDuration: *leaseDuration,
+	})
+	if err != nil {
+		return err
+	}
+	return c.write(result, reservationHeader, [][]string{reservationRow(result)})
+}
+
+func release(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(flag.NewFlagSet("release", flag.ContinueOnError), args, "<base_cidr>", "<netmask_id>")
+	if err != nil {
+		return err
+	}
+	released, err := c.release(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	return c.write(released, reservationHeader, [][]string{reservationRow(released)})
+}
+
+func reassign(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("reassign", flag.ContinueOnError)
+	owner := flags.String("owner", "", "new owner of the reservation, keeps the current one if empty")
+	description := flags.String("description", "", "new description of the reservation, keeps the current one if empty")
+	args, err := c.parse(flags, args, "<base_cidr>", "<netmask_id>", "<new_netmask_id>")
+	if err != nil {
+		return err
+	}
+	baseCidr, netmaskId, newNetmaskId := args[0], args[1], args[2]
+	if _, ok := c.newConnector(baseCidr).(connector.Reserver); ok {
+		return classify(errInvalid, "The http backend only reserves and releases reservations, reassigning them is not supported by its reservation service!")
+	}
+	var result connector.Reservation
+	err = c.update(ctx, baseCidr, func(networkConfig *connector.NetworkConfig) error {
+		subnet, contains := networkConfig.Subnets[netmaskId]
+		if !contains {
+			return fmt.Errorf("Netmask with id %s does not exist in %s!", netmaskId, baseCidr)
+		}
+		if networkConfig.ChildPools[netmaskId] != "" && netmaskId != newNetmaskId {
+			return fmt.Errorf("The netmaskId %s has been promoted into a child pool, which cannot be renamed!", netmaskId)
+		}
+		if _, contains := networkConfig.Subnets[newNetmaskId]; contains && netmaskId != newNetmaskId {
+			return fmt.Errorf("The netmaskId %s already exists!", newNetmaskId)
+		}
+		metadata := networkConfig.Metadata[netmaskId]
+		lease, leased := networkConfig.Leases[netmaskId]
+		delete(networkConfig.Subnets, netmaskId)
+		delete(networkConfig.Metadata, netmaskId)
+		delete(networkConfig.Leases, netmaskId)
+		if *owner != "" {
+			metadata.Owner = *owner
+		}
+		if *description != "" {
+			metadata.Description = *description
+		}
+		networkConfig.Subnets[newNetmaskId] = subnet
+		networkConfig.SetMetadata(newNetmaskId, metadata)
+		if leased {
+			networkConfig.Leases[newNetmaskId] = lease
+		}
+		result = networkConfig.Reservation(newNetmaskId)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.write(result, reservationHeader, [][]string{reservationRow(result)})
+}
+
+func free(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(flag.NewFlagSet("free", flag.ContinueOnError), args, "<base_cidr>")
+	if err != nil {
+		return err
+	}
+	networkConfig, err := c.read(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	occupied := networkConfig.OccupiedSubnets()
+	usage, err := cidrCalculator.GetUsage(&occupied, args[0])
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.write(map[string]interface{}{
+			"total_addresses": usage.TotalAddresses.String(),
+			"used_addresses":  usage.UsedAddresses.String(),
+			"free_netmasks":   usage.FreeNetmasks,
+		}, nil, nil)
+	}
+	fmt.Fprintf(c.stdout, "TOTAL_ADDRESSES: %s\nUSED_ADDRESSES:  %s\n\n", usage.TotalAddresses, usage.UsedAddresses)
+	rows := make([][]string, 0, len(usage.FreeNetmasks))
+	for _, netmask := range usage.FreeNetmasks {
+		rows = append(rows, []string{netmask})
+	}
+	return c.write(nil, []string{"FREE_NETMASK"}, rows)
+}
+
+func history(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	netmaskId := flags.String("netmask-id", "", "lists only the changes of this netmask_id")
+	cidr := flags.String("cidr", "", "lists only the changes of cidr ranges overlapping this one")
+	args, err := c.parse(flags, args, "<base_cidr>")
+	if err != nil {
+		return err
+	}
+	var ipNet *net.IPNet
+	if *cidr != "" {
+		if _, ipNet, err = net.ParseCIDR(*cidr); err != nil {
+			return err
+		}
+	}
+	networkConfig, err := c.read(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return writeHistory(c, connector.FilterHistory(networkConfig.History, *netmaskId, ipNet))
+}
+
+func gc(ctx context.Context, c *cli, args []string) error {
+	args, err := c.parse(flag.NewFlagSet("gc", flag.ContinueOnError), args, "<base_cidr>")
+	if err != nil {
+		return err
+	}
+	reclaimed, err := connector.CollectGarbage(ctx, c.newConnector(args[0]))
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(reclaimed))
+	for _, netmaskId := range reclaimed {
+		rows = append(rows, []string{netmaskId})
+	}
+	return c.write(reclaimed, []string{"RECLAIMED_NETMASK_ID"}, rows)
+}
+
+func writeHistory(c *cli, events []connector.AuditEvent) error {
+	rows := make([][]string, 0, len(events))
+	for _, event := range events {
+		rows = append(rows, []string{event.Timestamp, event.Action, event.NetmaskId, event.OldNetmaskId, event.OldCidr, event.NewCidr, event.Caller})
+	}
+	return c.write(events, []string{"TIMESTAMP", "ACTION", "NETMASK_ID", "OLD_NETMASK_ID", "OLD_CIDR", "NEW_CIDR", "CALLER"}, rows)
+}
+
+// lessCidr orders cidr ranges by their network address.
+func lessCidr(a string, b string) bool {
+	_, ipNetA, errA := net.ParseCIDR(a)
+	_, ipNetB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return bytes.Compare(ipNetA.IP.To16(), ipNetB.IP.To16()) < 0
+}
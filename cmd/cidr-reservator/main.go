@@ -0,0 +1,117 @@
+// Command cidr-reservator lists, reserves and releases cidr ranges without writing Terraform. It reads and writes the
+// same reservation documents as the provider, so both can be used side by side.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// cli holds the global settings all commands share.
+type cli struct {
+	newConnector connector.Factory
+	output       string
+	stdout       io.Writer
+	stderr       io.Writer
+}
+
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"pools":    {"pools", "Lists the base cidr ranges with reservations.", pools},
+	"list":     {"list <base_cidr>", "Lists the reservations of a base cidr range.", list},
+	"show":     {"show <base_cidr> <netmask_id>", "Shows a reservation together with its history.", show},
+	"reserve":  {"reserve [flags] <base_cidr> <netmask_id>", "Reserves the next free cidr range, or the one given by -cidr.", reserve},
+	"release":  {"release <base_cidr> <netmask_id>", "Releases a reservation.", release},
+	"reassign": {"reassign [flags] <base_cidr> <netmask_id> <new_netmask_id>", "Hands a reservation over to a new netmask_id, keeping its cidr range.", reassign},
+	"free":     {"free <base_cidr>", "Shows the usage and the free cidr ranges of a base cidr range.", free},
+	"history":  {"history [flags] <base_cidr>", "Lists the recorded changes of the reservations of a base cidr range.", history},
+	"gc":       {"gc <base_cidr>", "Reclaims expired leases and drops expired quarantine tombstones.", gc},
+	"serve":    {"serve [flags]", "Serves the reservations over an HTTP API.", serve},
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := flag.NewFlagSet("cidr-reservator", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		usage(flags, stderr)
+	}
+	options := connector.Options{}
+	flags.StringVar(&options.Backend, "backend", envOrDefault("CIDR_RESERVATOR_BACKEND", "gcs"), "storage backend, one of "+strings.Join(connector.Backends, ", "))
+	flags.StringVar(&options.Bucket, "bucket", os.Getenv("CIDR_RESERVATOR_BUCKET"), "bucket of the gcs and s3 backends, container of the azure backend")
+	flags.StringVar(&options.LocalDirectory, "local-directory", os.Getenv("CIDR_RESERVATOR_LOCAL_DIRECTORY"), "directory of the local backend")
+	flags.StringVar(&options.S3.Region, "s3-region", "", "region of the s3 backend")
+	flags.StringVar(&options.S3.Endpoint, "s3-endpoint", "", "endpoint of an S3 compatible storage")
+	flags.BoolVar(&options.S3.UsePathStyle, "s3-use-path-style", false, "use path-style addressing with the s3 backend")
+	flags.StringVar(&options.Azure.ConnectionString, "azure-connection-string", os.Getenv("AZURE_STORAGE_CONNECTION_STRING"), "connection string of the azure backend")
+	flags.StringVar(&options.Azure.AccountUrl, "azure-account-url", "", "storage account url of the azure backend")
+	flags.StringVar(&options.ConsulKeyPrefix, "consul-key-prefix", "cidr-reservator", "KV path of the consul backend")
+	flags.StringVar(&options.Consul.Address, "consul-address", "", "address of the consul backend")
+	flags.StringVar(&options.Consul.Token, "consul-token", "", "ACL token of the consul backend")
+	flags.StringVar(&options.PostgresConnectionString, "postgres-connection-string", os.Getenv("CIDR_RESERVATOR_POSTGRES_CONNECTION_STRING"), "connection string of the postgres backend")
+	flags.StringVar(&options.Http.Address, "http-address", os.Getenv("CIDR_RESERVATOR_HTTP_ADDRESS"), "URL of the http backend")
+	flags.StringVar(&options.Http.Token, "http-token", os.Getenv("CIDR_RESERVATOR_HTTP_TOKEN"), "bearer token of the http backend")
+	caller := flags.String("caller", envOrDefault("CIDR_RESERVATOR_CALLER", connector.DefaultCaller()), "who is recorded in the history of changes")
+	output := flags.String("output", "table", "output format, table or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("output %s is not supported!", *output)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("No command given!")
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("Unknown command %s!", flags.Arg(0))
+	}
+	factory, err := connector.NewFactory(ctx, options)
+	if err != nil {
+		return err
+	}
+	c := &cli{newConnector: factory, output: *output, stdout: stdout, stderr: stderr}
+	return command.run(connector.WithCaller(ctx, *caller), c, flags.Args()[1:])
+}
+
+func usage(flags *flag.FlagSet, stderr io.Writer) {
+	fmt.Fprintln(stderr, "Usage: cidr-reservator [flags] <command> [arguments]")
+	fmt.Fprintln(stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(stderr, "  %-60s %s\n", commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(stderr, "\nFlags:")
+	flags.PrintDefaults()
+}
+
+func envOrDefault(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func runCli(t *testing.T, directory string, args ...string) string {
+	var stdout bytes.Buffer
+	args = append([]string{"-backend", "local", "-local-directory", directory, "-caller", "alice"}, args...)
+	if err := run(context.Background(), args, &stdout, io.Discard); err != nil {
+		t.Fatalf("%v failed: %s", args, err)
+	}
+	return stdout.String()
+}
+
+func TestReserveListAndRelease(t *testing.T) {
+	directory := t.TempDir()
+	runCli(t, directory, "reserve", "-prefix-length", "24", "-owner", "team-a", "-label", "environment=test", "10.116.0.0/14", "first")
+	runCli(t, directory, "reserve", "-cidr", "10.116.8.0/22", "10.116.0.0/14", "second")
+
+	var reservations []connector.Reservation
+	if err := json.Unmarshal([]byte(runCli(t, directory, "-output", "json", "list", "10.116.0.0/14")), &reservations); err != nil {
+		t.Fatal(err)
+	}
+	expected := []connector.Reservation{
+		{NetmaskId: "first", Cidr: "10.116.0.0/24", Owner: "team-a", Labels: map[string]string{"environment": "test"}},
+		{NetmaskId: "second", Cidr: "10.116.8.0/22"},
+	}
+	if !reflect.DeepEqual(reservations, expected) {
+		t.Fatalf("Unexpected reservations %+v", reservations)
+	}
+	if table := runCli(t, directory, "list", "10.116.0.0/14"); !strings.Contains(table, "first       10.116.0.0/24  team-a") {
+		t.Fatalf("Unexpected table\n%s", table)
+	}
+	var baseCidrs []string
+	if err := json.Unmarshal([]byte(runCli(t, directory, "-output", "json", "pools")), &baseCidrs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(baseCidrs, []string{"10.116.0.0/14"}) {
+		t.Fatalf("Unexpected pools %v", baseCidrs)
+	}
+
+	runCli(t, directory, "reassign", "-owner", "team-b", "10.116.0.0/14", "first", "renamed")
+	runCli(t, directory, "release", "10.116.0.0/14", "second")
+	var history []map[string]string
+	if err := json.Unmarshal([]byte(runCli(t, directory, "-output", "json", "history", "-cidr", "10.116.0.0/24", "10.116.0.0/14")), &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0]["action"] != "allocate" || history[1]["action"] != "rename" || history[1]["caller"] != "alice" {
+		t.Fatalf("Unexpected history %v", history)
+	}
+	var usage map[string]interface{}
+	if err := json.Unmarshal([]byte(runCli(t, directory, "-output", "json", "free", "10.116.0.0/14")), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if usage["used_addresses"] != "256" {
+		t.Fatalf("Unexpected usage %v", usage)
+	}
+}
+
+func TestReserveRequiresPrefixLengthOrCidr(t *testing.T) {
+	args := []string{"-backend", "local", "-local-directory", t.TempDir(), "reserve", "10.116.0.0/14", "first"}
+	if err := run(context.Background(), args, io.Discard, io.Discard); err == nil {
+		t.Fatal("reserve without -prefix-length and -cidr should fail")
+	}
+}
+
+func TestBaseCidrWithHostBitsIsRejected(t *testing.T) {
+	args := []string{"-backend", "local", "-local-directory", t.TempDir(), "reserve", "-prefix-length", "24", "10.116.1.0/14", "first"}
+	if err := run(context.Background(), args, io.Discard, io.Discard); err == nil || !strings.Contains(err.Error(), "use 10.116.0.0/14 instead") {
+		t.Fatalf("Expected the base cidr with host bits to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// callerHeader names the HTTP header, by which clients tell on whose behalf they reserve, e.g. the user of a portal.
+const callerHeader = "X-Cidr-Reservator-Caller"
+
+// server exposes the reservations over HTTP. Changes of the same base cidr range are serialized in memory, so
+// concurrent requests to one server do not race for the reservation document. Several servers, the provider and the
+// command line still rely on the conditional writes of the connectors.
+type server struct {
+	cli   *cli
+	token string
+	mutex sync.Mutex
+	pools map[string]*sync.Mutex
+}
+
+func newServer(c *cli, token string) *server {
+	return &server{cli: c, token: token, pools: make(map[string]*sync.Mutex)}
+}
+
+func serve(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := flags.String("listen", ":8080", "address to listen on")
+	token := flags.String("token", os.Getenv("CIDR_RESERVATOR_API_TOKEN"), "bearer token clients have to send, no authentication if empty")
+	if _, err := c.parse(flags, args); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           newServer(c, *token),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	fmt.Fprintf(c.stderr, "Serving reservations on %s\n", *listen)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP routes
+//
+//	GET    /v1/pools
+//	GET    /v1/pools/<base_cidr>/reservations
+//	POST   /v1/pools/<base_cidr>/reservations
+//	GET    /v1/pools/<base_cidr>/reservations/<netmask_id>
+//	DELETE /v1/pools/<base_cidr>/reservations/<netmask_id>
+//	GET    /v1/pools/<base_cidr>/free
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, errors.New("Missing or invalid bearer token!"))
+		return
+	}
+	ctx := r.Context()
+	if caller := r.Header.Get(callerHeader); caller != "" {
+		ctx = connector.WithCaller(ctx, caller)
+	}
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	if len(segments) < 2 || segments[0] != "v1" || segments[1] != "pools" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s!", r.URL.Path))
+		return
+	}
+	if len(segments) == 2 {
+		s.route(w, r, map[string]func() (int, interface{}, error){
+			http.MethodGet: func() (int, interface{}, error) {
+				baseCidrs, err := connector.ListBaseCidrs(ctx, s.cli.newConnector("0.0.0.0/0"))
+				return http.StatusOK, baseCidrs, err
+			},
+		})
+		return
+	}
+	// the base cidr spans two segments, e.g. /v1/pools/10.116.0.0/14/reservations
+	if len(segments) < 5 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s!", r.URL.Path))
+		return
+	}
+	baseCidr, err := url.PathUnescape(segments[2] + "/" + segments[3])
+	if err == nil {
+		err = verifyBaseCidr(baseCidr)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("The base cidr of %s is invalid: %s", r.URL.Path, err))
+		return
+	}
+	switch {
+	case len(segments) == 5 && segments[4] == "reservations":
+		s.route(w, r, map[string]func() (int, interface{}, error){
+			http.MethodGet: func() (int, interface{}, error) {
+				reservations, err := s.cli.reservations(ctx, baseCidr)
+				return http.StatusOK, reservations, err
+			},
+			http.MethodPost: func() (int, interface{}, error) {
+				var request connector.ReservationRequest
+				decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+				decoder.DisallowUnknownFields()
+				if err := decoder.Decode(&request); err != nil {
+					return 0, nil, classify(errInvalid, "The request body is invalid: %s", err)
+				}
+				defer s.lock(baseCidr)()
+				result, err := s.cli.reserve(ctx, baseCidr, request)
+				return http.StatusCreated, result, err
+			},
+		})
+	case len(segments) == 6 && segments[4] == "reservations":
+		netmaskId, err := url.PathUnescape(segments[5])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.route(w, r, map[string]func() (int, interface{}, error){
+			http.MethodGet: func() (int, interface{}, error) {
+				networkConfig, err := s.cli.read(ctx, baseCidr)
+				if err != nil {
+					return 0, nil, err
+				}
+				if _, contains := networkConfig.Subnets[netmaskId]; !contains {
+					return 0, nil, classify(errNotFound, "Netmask with id %s does not exist in %s!", netmaskId, baseCidr)
+				}
+				return http.StatusOK, networkConfig.Reservation(netmaskId), nil
+			},
+			http.MethodDelete: func() (int, interface{}, error) {
+				defer s.lock(baseCidr)()
+				released, err := s.cli.release(ctx, baseCidr, netmaskId)
+				return http.StatusOK, released, err
+			},
+		})
+	case len(segments) == 5 && segments[4] == "free":
+		s.route(w, r, map[string]func() (int, interface{}, error){
+			http.MethodGet: func() (int, interface{}, error) {
+				networkConfig, err := s.cli.read(ctx, baseCidr)
+				if err != nil {
+					return 0, nil, err
+				}
+				occupied := networkConfig.OccupiedSubnets()
+				usage, err := cidrCalculator.GetUsage(&occupied, baseCidr)
+				if err != nil {
+					return 0, nil, err
+				}
+				return http.StatusOK, map[string]interface{}{
+					"total_addresses": usage.TotalAddresses.String(),
+					"used_addresses":  usage.UsedAddresses.String(),
+					"free_netmasks":   usage.FreeNetmasks,
+				}, nil
+			},
+		})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s!", r.URL.Path))
+	}
+}
+
+// route runs the handler of the request method and writes its result as JSON.
+func (s *server) route(w http.ResponseWriter, r *http.Request, handlers map[string]func() (int, interface{}, error)) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s is not allowed on %s!", r.Method, r.URL.Path))
+		return
+	}
+	status, value, err := handler()
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	writeJson(w, status, value)
+}
+
+// lock serializes the changes of baseCidr and returns the function to unlock it again.
+func (s *server) lock(baseCidr string) func() {
+	s.mutex.Lock()
+	pool, ok := s.pools[baseCidr]
+	if !ok {
+		pool = &sync.Mutex{}
+		s.pools[baseCidr] = pool
+	}
+	s.mutex.Unlock()
+	pool.Lock()
+	return pool.Unlock
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, errInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errExists), errors.Is(err, connector.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, errUnsatisfiable):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestServer(t *testing.T, token string) *httptest.Server {
+	c := &cli{newConnector: connector.NewLocalFactory(t.TempDir()), output: "json", stdout: io.Discard, stderr: io.Discard}
+	server := httptest.NewServer(newServer(c, token))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func request(t *testing.T, method string, url string, body string, value interface{}) int {
+	status, err := send(method, url, body, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// send is request for goroutines other than the one of the test, which must not call t.Fatal.
+func send(method string, url string, body string, value interface{}) (int, error) {
+	httpRequest, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpRequest.Header.Set(callerHeader, "portal")
+	response, err := http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	if value != nil {
+		if err := json.NewDecoder(response.Body).Decode(value); err != nil {
+			return 0, err
+		}
+	}
+	return response.StatusCode, nil
+}
+
+func TestServerReservesAndReleases(t *testing.T) {
+	server := newTestServer(t, "")
+	pool := server.URL + "/v1/pools/10.116.0.0/14"
+	var created connector.Reservation
+	if status := request(t, http.MethodPost, pool+"/reservations", `{"netmask_id":"first","prefix_length":24,"owner":"team-a"}`, &created); status != http.StatusCreated {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if created.Cidr != "10.116.0.0/24" || created.Owner != "team-a" {
+		t.Fatalf("Unexpected reservation %+v", created)
+	}
+	if status := request(t, http.MethodPost, pool+"/reservations", `{"netmask_id":"first","prefix_length":24}`, nil); status != http.StatusConflict {
+		t.Fatalf("Reserving an existing netmask_id returned %d", status)
+	}
+	if status := request(t, http.MethodPost, pool+"/reservations", `{"netmask_id":"huge","prefix_length":8}`, nil); status != http.StatusUnprocessableEntity {
+		t.Fatalf("Reserving a range bigger than the base cidr returned %d", status)
+	}
+	var fetched connector.Reservation
+	if status := request(t, http.MethodGet, pool+"/reservations/first", "", &fetched); status != http.StatusOK || !reflect.DeepEqual(fetched, created) {
+		t.Fatalf("Unexpected reservation %+v with status %d", fetched, status)
+	}
+	var baseCidrs []string
+	if request(t, http.MethodGet, server.URL+"/v1/pools", "", &baseCidrs); len(baseCidrs) != 1 || baseCidrs[0] != "10.116.0.0/14" {
+		t.Fatalf("Unexpected pools %v", baseCidrs)
+	}
+	if status := request(t, http.MethodDelete, pool+"/reservations/first", "", nil); status != http.StatusOK {
+		t.Fatalf("Unexpected status %d", status)
+	}
+	if status := request(t, http.MethodGet, pool+"/reservations/first", "", nil); status != http.StatusNotFound {
+		t.Fatalf("Released reservation returned %d", status)
+	}
+	var usage map[string]interface{}
+	if request(t, http.MethodGet, pool+"/free", "", &usage); usage["used_addresses"] != "0" {
+		t.Fatalf("Unexpected usage %v", usage)
+	}
+}
+
+func TestServerRejectsBaseCidrWithHostBits(t *testing.T) {
+	server := newTestServer(t, "")
+	var response map[string]string
+	if status := request(t, http.MethodGet, server.URL+"/v1/pools/10.116.1.0/14/reservations", "", &response); status != http.StatusBadRequest {
+		t.Fatalf("Base cidr with host bits returned %d", status)
+	}
+	if !strings.Contains(response["error"], "use 10.116.0.0/14 instead") {
+		t.Fatalf("Unexpected error %q", response["error"])
+	}
+}
+
+func TestServerSerializesReservationsPerPool(t *testing.T) {
+	server := newTestServer(t, "")
+	var wg sync.WaitGroup
+	statuses := make([]int, 16)
+	errs := make([]error, len(statuses))
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"netmask_id":"network-%d","prefix_length":24}`, i)
+			statuses[i], errs[i] = send(http.MethodPost, server.URL+"/v1/pools/10.116.0.0/14/reservations", body, nil)
+		}(i)
+	}
+	wg.Wait()
+	for i, status := range statuses {
+		if errs[i] != nil {
+			t.Fatalf("Reservation %d failed: %s", i, errs[i])
+		}
+		if status != http.StatusCreated {
+			t.Fatalf("Reservation %d returned %d", i, status)
+		}
+	}
+	var reservations []connector.Reservation
+	request(t, http.MethodGet, server.URL+"/v1/pools/10.116.0.0/14/reservations", "", &reservations)
+	if len(reservations) != len(statuses) {
+		t.Fatalf("Expected %d reservations, got %d", len(statuses), len(reservations))
+	}
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	server := newTestServer(t, "secret")
+	if status := request(t, http.MethodGet, server.URL+"/v1/pools", "", nil); status != http.StatusUnauthorized {
+		t.Fatalf("Request without token returned %d", status)
+	}
+	httpRequest, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/pools", nil)
+	httpRequest.Header.Set("Authorization", "Bearer secret")
+	response, err := http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Request with token returned %d", response.StatusCode)
+	}
+}
+
+func TestProviderReservesThroughServer(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, "")
+	cidrReservator := provider.New("test")()
+	diags := cidrReservator.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"backend":      "http",
+		"http_address": server.URL,
+		"caller":       "terraform",
+	}))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	resource := cidrReservator.ResourcesMap["cidr-reservator_network_request"]
+	networkRequest := func(netmaskId string, owner string) *schema.ResourceData {
+		return schema.TestResourceDataRaw(t, resource.Schema, map[string]interface{}{
+			"base_cidr":      "10.116.0.0/14",
+			"netmask_id":     netmaskId,
+			"prefix_length":  24,
+			"owner":          owner,
+			"lease_duration": "1h",
+		})
+	}
+	first := networkRequest("first", "team-a")
+	if diags := resource.CreateContext(ctx, first, cidrReservator.Meta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	// the location of the http backend contains colons itself
+	if first.Id() != server.URL+":10.116.0.0/14:first" || first.Get("netmask") != "10.116.0.0/24" || first.Get("expires_at") == "" {
+		t.Fatalf("Unexpected id %s, netmask %s or expires_at %s", first.Id(), first.Get("netmask"), first.Get("expires_at"))
+	}
+	// reservations by other clients are taken into account by the server
+	var second connector.Reservation
+	if request(t, http.MethodPost, server.URL+"/v1/pools/10.116.0.0/14/reservations", `{"netmask_id":"second","prefix_length":24}`, &second); second.Cidr != "10.116.1.0/24" {
+		t.Fatalf("Unexpected reservation %+v", second)
+	}
+	third := networkRequest("third", "team-b")
+	if diags := resource.CreateContext(ctx, third, cidrReservator.Meta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if third.Get("netmask") != "10.116.2.0/24" {
+		t.Fatalf("Expected the server to reserve 10.116.2.0/24, got %s", third.Get("netmask"))
+	}
+	if diags := resource.CreateContext(ctx, networkRequest("second", ""), cidrReservator.Meta()); !diags.HasError() || !strings.Contains(diags[0].Summary, "409 Conflict") {
+		t.Fatalf("Reserving an existing netmask_id should be rejected by the server, got %v", diags)
+	}
+
+	if diags := resource.ReadContext(ctx, first, cidrReservator.Meta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if first.Id() == "" || first.Get("owner") != "team-a" || first.Get("lease_duration") != "1h" {
+		t.Fatalf("Unexpected state after refresh %v", first.State())
+	}
+	imported := resource.Data(nil)
+	imported.SetId(first.Id())
+	if _, err := resource.Importer.StateContext(ctx, imported, cidrReservator.Meta()); err != nil {
+		t.Fatal(err)
+	}
+	if imported.Get("base_cidr") != "10.116.0.0/14" || imported.Get("netmask_id") != "first" || imported.Get("netmask") != "10.116.0.0/24" {
+		t.Fatalf("Unexpected imported state %v", imported.State())
+	}
+
+	for i := 0; i < 2; i++ {
+		if diags := resource.DeleteContext(ctx, first, cidrReservator.Meta()); diags.HasError() {
+			t.Fatalf("Releasing first the %d. time failed: %v", i+1, diags)
+		}
+	}
+	if diags := resource.ReadContext(ctx, first, cidrReservator.Meta()); diags.HasError() || first.Id() != "" {
+		t.Fatalf("A released reservation should be removed from the state, got %v", diags)
+	}
+}
+
+func TestProviderReservesRequestedCidrThroughServer(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, "")
+	cidrReservator := provider.New("test")()
+	diags := cidrReservator.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"backend":      "http",
+		"http_address": server.URL,
+	}))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	resource := cidrReservator.ResourcesMap["cidr-reservator_network_request"]
+	requested := schema.TestResourceDataRaw(t, resource.Schema, map[string]interface{}{
+		"base_cidr":      "10.116.0.0/14",
+		"netmask_id":     "requested",
+		"prefix_length":  24,
+		"requested_cidr": "10.116.5.0/24",
+	})
+	if diags := resource.CreateContext(ctx, requested, cidrReservator.Meta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if requested.Get("netmask") != "10.116.5.0/24" {
+		t.Fatalf("Expected the server to reserve 10.116.5.0/24, got %s", requested.Get("netmask"))
+	}
+	var reservation connector.Reservation
+	if request(t, http.MethodGet, server.URL+"/v1/pools/10.116.0.0/14/reservations/requested", "", &reservation); reservation.Cidr != "10.116.5.0/24" {
+		t.Fatalf("Unexpected reservation %+v", reservation)
+	}
+}
+
+func TestProviderRejectsDocumentChangesAtPlan(t *testing.T) {
+	ctx := context.Background()
+	server := newTestServer(t, "")
+	cidrReservator := provider.New("test")()
+	diags := cidrReservator.Configure(ctx, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"backend":      "http",
+		"http_address": server.URL,
+	}))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	resource := cidrReservator.ResourcesMap["cidr-reservator_network_request"]
+	config := map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "first",
+		"prefix_length": 24,
+	}
+	first := schema.TestResourceDataRaw(t, resource.Schema, config)
+	if diags := resource.CreateContext(ctx, first, cidrReservator.Meta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, err := resource.Diff(ctx, first.State(), terraform.NewResourceConfigRaw(config), cidrReservator.Meta()); err != nil {
+		t.Fatalf("Unchanged network requests should plan, got %v", err)
+	}
+	config["owner"] = "team-a"
+	if _, err := resource.Diff(ctx, first.State(), terraform.NewResourceConfigRaw(config), cidrReservator.Meta()); err == nil || !strings.Contains(err.Error(), "changing owner") {
+		t.Fatalf("Changing the owner should fail at plan, got %v", err)
+	}
+	exclusion := cidrReservator.ResourcesMap["cidr-reservator_exclusion"]
+	_, err := exclusion.Diff(ctx, nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"base_cidr":    "10.116.0.0/14",
+		"exclusion_id": "onprem",
+		"cidr":         "10.117.0.0/16",
+	}), cidrReservator.Meta())
+	if err == nil || !strings.Contains(err.Error(), "managing exclusions") {
+		t.Fatalf("Exclusions should fail at plan, got %v", err)
+	}
+}
+
+func TestCliReservesThroughServer(t *testing.T) {
+	server := newTestServer(t, "")
+	runHttp := func(args ...string) error {
+		return run(context.Background(), append([]string{"-backend", "http", "-http-address", server.URL, "-output", "json"}, args...), io.Discard, io.Discard)
+	}
+	if err := runHttp("reserve", "-prefix-length", "24", "10.116.0.0/14", "first"); err != nil {
+		t.Fatal(err)
+	}
+	var reservations []connector.Reservation
+	if request(t, http.MethodGet, server.URL+"/v1/pools/10.116.0.0/14/reservations", "", &reservations); len(reservations) != 1 || reservations[0].Cidr != "10.116.0.0/24" {
+		t.Fatalf("Unexpected reservations %+v", reservations)
+	}
+	if err := runHttp("reassign", "10.116.0.0/14", "first", "renamed"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("Reassigning should be rejected before writing, got %v", err)
+	}
+	if err := runHttp("release", "10.116.0.0/14", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runHttp("release", "10.116.0.0/14", "first"); err == nil {
+		t.Fatal("Releasing an unknown reservation should fail")
+	}
+}
@@ -14,4 +14,9 @@ resource "cidr-reservator_network_request" "network_request2" {
   prefix_length = 26
   base_cidr     = "10.6.0.0/18"
   netmask_id    = "test"
-}
\ No newline at end of file
+}
+
+data "cidr-reservator_network_request" "network_request" {
+  base_cidr  = cidr-reservator_network_request.network_request.base_cidr
+  netmask_id = cidr-reservator_network_request.network_request.netmask_id
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+)
+
+// A reservation promoted into a child pool is listed in ChildPools of its base cidr range, while the reservation
+// document of the child pool, which is keyed by the reserved netmask, refers back to it as Parent. Both have to match,
+// before anything is reserved within the child pool.
+
+// promoteChildPool links the reservation document of netmask as child pool to the reservation netmaskId of
+// parentBaseCidr. Existing reservations within netmask are adopted. If that fails, e.g. as netmask is already the
+// child pool of another reservation, netmask is removed from the child pools of parentBaseCidr again.
+func promoteChildPool(ctx context.Context, m interface{}, parentBaseCidr string, netmaskId string, netmask string) error {
+	err := retry(func() error {
+		return update(ctx, m, m.(*providerConfig).newConnector(netmask), func(networkConfig *connector.NetworkConfig) error {
+			parent := networkConfig.Parent
+			if parent != nil && !parent.Released && (parent.BaseCidr != parentBaseCidr || parent.NetmaskId != netmaskId) {
+				return fmt.Errorf("The base cidr %s is already a child pool of the reservation %s of %s!", netmask, parent.NetmaskId, parent.BaseCidr)
+			}
+			networkConfig.Parent = &connector.ParentReservation{BaseCidr: parentBaseCidr, NetmaskId: netmaskId}
+			return nil
+		})
+	})
+	if err == nil {
+		return nil
+	}
+	rollbackErr := retry(func() error {
+		return update(ctx, m, m.(*providerConfig).newConnector(parentBaseCidr), func(networkConfig *connector.NetworkConfig) error {
+			if networkConfig.ChildPools[netmaskId] == netmask {
+				delete(networkConfig.ChildPools, netmaskId)
+			}
+			return nil
+		})
+	})
+	if rollbackErr != nil {
+		return fmt.Errorf("%s Removing the child pool from the reservation %s failed as well: %s", err, netmaskId, rollbackErr)
+	}
+	return err
+}
+
+// releaseChildPool marks the child pool netmask of the reservation netmaskId of parentBaseCidr as released, unless it
+// still has reservations. Child pools of other reservations are left alone. The conditional write makes concurrent
+// reservations within the child pool fail instead of ending up in a released pool.
+func releaseChildPool(ctx context.Context, m interface{}, parentBaseCidr string, netmaskId string, netmask string) error {
+	return update(ctx, m, m.(*providerConfig).newConnector(netmask), func(networkConfig *connector.NetworkConfig) error {
+		parent := networkConfig.Parent
+		if parent == nil || parent.Released || parent.BaseCidr != parentBaseCidr || parent.NetmaskId != netmaskId {
+			return nil
+		}
+		if len(networkConfig.Subnets) > 0 {
+			return fmt.Errorf("The child pool %s still has %d reservations and cannot be released!", netmask, len(networkConfig.Subnets))
+		}
+		parent.Released = true
+		return nil
+	})
+}
+
+// childPoolOf returns the child pool the reservation netmaskId has been promoted into, or "" if there is none.
+func childPoolOf(ctx context.Context, remoteConnector connector.Connector, netmaskId string) (string, error) {
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if errors.Is(err, connector.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return networkConfig.ChildPools[netmaskId], nil
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"strings"
+	"testing"
+)
+
+func newChildPoolRequest(t *testing.T, baseCidr string, netmaskId string, prefixLength int, childPool bool) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":     baseCidr,
+		"netmask_id":    netmaskId,
+		"prefix_length": prefixLength,
+		"child_pool":    childPool,
+	})
+}
+
+func TestChildPoolCannotBeReleasedWhileItHasReservations(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	businessUnit := newChildPoolRequest(t, "10.0.0.0/8", "business-unit", 16, true)
+	if diags := resourceServerCreate(ctx, businessUnit, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	team := newChildPoolRequest(t, businessUnit.Get("netmask").(string), "team", 24, false)
+	if diags := resourceServerCreate(ctx, team, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if team.Get("netmask") != "10.0.0.0/24" {
+		t.Fatalf("Unexpected netmask %s", team.Get("netmask"))
+	}
+	if diags := resourceServerDelete(ctx, businessUnit, meta); !diags.HasError() {
+		t.Fatal("Releasing a child pool with reservations should fail")
+	}
+	if diags := resourceServerDelete(ctx, team, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerDelete(ctx, businessUnit, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerCreate(ctx, newChildPoolRequest(t, "10.0.0.0/16", "late", 24, false), meta); !diags.HasError() {
+		t.Fatal("Reserving within a released child pool should fail")
+	}
+}
+
+func TestChildPoolCannotBeResized(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	businessUnit := newChildPoolRequest(t, "10.0.0.0/8", "business-unit", 16, true)
+	if diags := resourceServerCreate(ctx, businessUnit, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resized := updateData(t, resourceServer(), businessUnit, map[string]interface{}{
+		"base_cidr":     "10.0.0.0/8",
+		"netmask_id":    "business-unit",
+		"prefix_length": 15,
+		"child_pool":    true,
+	})
+	if diags := resourceServerUpdate(ctx, resized, meta); !diags.HasError() || !strings.Contains(diags[0].Summary, "can neither be renamed nor resized") {
+		t.Fatalf("Resizing a child pool should fail, got %v", diags)
+	}
+}
+
+func TestReservingFailsInChildPoolNotMatchingItsParent(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if err := promoteChildPool(ctx, meta, "10.0.0.0/8", "business-unit", "10.0.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceServerCreate(ctx, newChildPoolRequest(t, "10.0.0.0/16", "team", 24, false), meta); !diags.HasError() {
+		t.Fatal("Reserving within a child pool without a matching parent reservation should fail")
+	}
+}
+
+func TestFailedPromotionLeavesChildPoolOfOtherReservationAlone(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if err := promoteChildPool(ctx, meta, "10.0.0.0/8", "other", "10.0.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	businessUnit := newChildPoolRequest(t, "10.0.0.0/8", "business-unit", 16, true)
+	if diags := resourceServerCreate(ctx, businessUnit, meta); !diags.HasError() {
+		t.Fatal("Promoting into the child pool of another reservation should fail")
+	}
+	parentConfig, err := meta.newConnector("10.0.0.0/8").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, contains := parentConfig.ChildPools["business-unit"]; contains {
+		t.Fatalf("The failed promotion should not be kept, got %v", parentConfig.ChildPools)
+	}
+	if diags := resourceServerDelete(ctx, businessUnit, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	childConfig, err := meta.newConnector("10.0.0.0/16").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent := childConfig.Parent; parent.NetmaskId != "other" || parent.Released {
+		t.Fatalf("The child pool of the other reservation should be left alone, got %+v", parent)
+	}
+}
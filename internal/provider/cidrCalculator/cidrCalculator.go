@@ -2,36 +2,90 @@ package cidrCalculator
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"github.com/apparentlymart/go-cidr/cidr"
+	"math/big"
 	"net"
 	"sort"
-	"strconv"
-	"strings"
 )
 
+// Strategy determines which free block GetNextNetmask picks.
+type Strategy string
+
+const (
+	// GapFill fills gaps starting at reserved subnets with the same or a smaller prefix size, before appending.
+	GapFill Strategy = ""
+	// FirstFit picks the lowest free address.
+	FirstFit Strategy = "first_fit"
+	// BestFit picks the smallest free block the new subnet fits into, preserving bigger blocks.
+	BestFit Strategy = "best_fit"
+	// Append never backfills and picks the first free address after the highest reserved subnet.
+	Append Strategy = "append"
+)
+
+// Strategies are all strategies, which can be chosen explicitly.
+var Strategies = []Strategy{FirstFit, BestFit, Append}
+
+// StrategyNames returns the names of all Strategies.
+func StrategyNames() []string {
+	names := make([]string, 0, len(Strategies))
+	for _, strategy := range Strategies {
+		names = append(names, string(strategy))
+	}
+	return names
+}
+
+// ParseStrategy returns the strategy called name; an empty name stands for the default GapFill.
+func ParseStrategy(name string) (Strategy, error) {
+	for _, strategy := range append(Strategies, GapFill) {
+		if string(strategy) == name {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("The allocation strategy %s is not supported!", name)
+}
+
+// NextNetmask returns the next free subnet of prefixLength within baseCidrRange, which is picked by strategy.
+func NextNetmask(currentSubnets *map[string]string, prefixLength int, baseCidrRange string, strategy Strategy) (string, error) {
+	calculator, err := New(currentSubnets, prefixLength, baseCidrRange)
+	if err != nil {
+		return "", err
+	}
+	return calculator.WithStrategy(strategy).GetNextNetmask()
+}
+
 type cidrCalculator struct {
 	currentSubnets       *map[string]string
-	prefixLength         int8
+	prefixLength         int
 	baseCidrRange        string
-	baseCidrPrefixLength int8
+	baseCidrPrefixLength int
 	baseIPNet            *net.IPNet
+	// addressBits is 32 for IPv4 and 128 for IPv6 base cidr ranges
+	addressBits int
+	strategy    Strategy
 }
 
-func New(currentSubnets *map[string]string, prefixLength int8, baseCidrRange string) (cidrCalculator, error) {
-	baseCidrPrefixLength, err := strconv.ParseInt(strings.Split(baseCidrRange, "/")[1], 10, 8)
+func New(currentSubnets *map[string]string, prefixLength int, baseCidrRange string) (cidrCalculator, error) {
+	_, baseIPNet, err := net.ParseCIDR(baseCidrRange)
 	if err != nil {
 		return cidrCalculator{}, err
 	}
-	_, baseIPNet, _ := net.ParseCIDR(baseCidrRange)
-	return cidrCalculator{currentSubnets, prefixLength, baseCidrRange, int8(baseCidrPrefixLength) - 1, baseIPNet}, nil
+	baseCidrPrefixLength, addressBits := baseIPNet.Mask.Size()
+	return cidrCalculator{currentSubnets, prefixLength, baseCidrRange, baseCidrPrefixLength - 1, baseIPNet, addressBits, GapFill}, nil
+}
+
+// WithStrategy returns a copy of the calculator using the given strategy.
+func (c cidrCalculator) WithStrategy(strategy Strategy) cidrCalculator {
+	c.strategy = strategy
+	return c
 }
 
-// TODO: implement!
 func (c cidrCalculator) GetNextNetmask() (string, error) {
-	if c.prefixLength > 32 || c.prefixLength < 0 {
-		return "", errors.New("prefixLength must be an integer between 0 and 32")
+	if c.prefixLength > c.addressBits || c.prefixLength < 0 {
+		return "", fmt.Errorf("prefixLength must be an integer between 0 and %d", c.addressBits)
+	}
+	if c.prefixLength <= c.baseCidrPrefixLength {
+		return "", fmt.Errorf("prefixLength %d must not be smaller than the prefix length of baseCidrRange %s", c.prefixLength, c.baseCidrRange)
 	}
 	ipNets := make([]*net.IPNet, 0, len(*c.currentSubnets))
 	for _, value := range *c.currentSubnets {
@@ -39,6 +93,9 @@ func (c cidrCalculator) GetNextNetmask() (string, error) {
 		if err != nil {
 			return "", err
 		}
+		if _, bits := ipNet.Mask.Size(); bits != c.addressBits {
+			return "", fmt.Errorf("Subnet %s does not belong to the address family of baseCidrRange %s!", value, c.baseCidrRange)
+		}
 		ipNets = append(ipNets, ipNet)
 	}
 	sort.Slice(ipNets, func(i, j int) bool {
@@ -49,14 +106,16 @@ func (c cidrCalculator) GetNextNetmask() (string, error) {
 	//	ipNetsCidr = append(ipNetsCidr, ipNet.String())
 	//}
 	var nextIPNet *net.IPNet
-	firstCidrSubnet, err := cidr.Subnet(c.baseIPNet, int(c.prefixLength-c.baseCidrPrefixLength-1), 0)
-	if err != nil {
-		return "", err
-	}
-	if len(ipNets) == 0 || (cidr.VerifyNoOverlap(append(ipNets, firstCidrSubnet), c.baseIPNet) == nil) {
-		nextIPNet = firstCidrSubnet
-	} else {
-		nextIPNet, err = c.recursivelyFindNextNetmask(&ipNets, c.prefixLength, false)
+	var err error
+	switch c.strategy {
+	case GapFill:
+		nextIPNet, err = c.fillGaps(ipNets)
+	case FirstFit, BestFit:
+		nextIPNet, err = c.fitFreeBlock(ipNets)
+	case Append:
+		nextIPNet, err = c.appendAfterLast(ipNets)
+	default:
+		err = fmt.Errorf("Unknown allocation strategy %s!", c.strategy)
 	}
 	if err != nil {
 		return "", err
@@ -72,18 +131,81 @@ func (c cidrCalculator) GetNextNetmask() (string, error) {
 	return nextIPNet.String(), nil
 }
 
+func (c cidrCalculator) fillGaps(ipNets []*net.IPNet) (*net.IPNet, error) {
+	firstCidrSubnet, err := cidr.Subnet(c.baseIPNet, c.prefixLength-c.baseCidrPrefixLength-1, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(ipNets) == 0 || (cidr.VerifyNoOverlap(append(ipNets, firstCidrSubnet), c.baseIPNet) == nil) {
+		return firstCidrSubnet, nil
+	}
+	return c.recursivelyFindNextNetmask(&ipNets, c.prefixLength, false)
+}
+
+// fitFreeBlock picks the lowest (FirstFit) or the smallest (BestFit) free block the new subnet fits into and
+// places the subnet at its start; free blocks are aligned to their size, so the start is aligned as well.
+func (c cidrCalculator) fitFreeBlock(ipNets []*net.IPNet) (*net.IPNet, error) {
+	ascending := make([]*net.IPNet, 0, len(ipNets))
+	for i := len(ipNets) - 1; i >= 0; i-- {
+		if c.baseIPNet.Contains(ipNets[i].IP) {
+			ascending = append(ascending, ipNets[i])
+		}
+	}
+	var chosen *net.IPNet
+	chosenPrefixLength := -1
+	for _, free := range freeIPNets(c.baseIPNet, ascending) {
+		freePrefixLength, _ := free.Mask.Size()
+		if freePrefixLength > c.prefixLength {
+			continue
+		}
+		if freePrefixLength > chosenPrefixLength {
+			chosen = free
+			chosenPrefixLength = freePrefixLength
+		}
+		if c.strategy == FirstFit {
+			break
+		}
+	}
+	if chosen == nil {
+		return nil, fmt.Errorf("baseCidrRange %s is exhausted!", c.baseCidrRange)
+	}
+	return &net.IPNet{IP: chosen.IP, Mask: net.CIDRMask(c.prefixLength, c.addressBits)}, nil
+}
+
+// appendAfterLast picks the first aligned subnet after the end of the highest reserved subnet.
+func (c cidrCalculator) appendAfterLast(ipNets []*net.IPNet) (*net.IPNet, error) {
+	next := ipToInt(c.baseIPNet.IP)
+	for _, ipNet := range ipNets {
+		if !c.baseIPNet.Contains(ipNet.IP) {
+			continue
+		}
+		_, lastIP := cidr.AddressRange(ipNet)
+		if end := new(big.Int).Add(ipToInt(lastIP), big.NewInt(1)); end.Cmp(next) > 0 {
+			next = end
+		}
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(c.addressBits-c.prefixLength))
+	if remainder := new(big.Int).Mod(next, size); remainder.Sign() != 0 {
+		next.Add(next, size).Sub(next, remainder)
+	}
+	_, lastBaseIP := cidr.AddressRange(c.baseIPNet)
+	if new(big.Int).Add(next, size).Cmp(new(big.Int).Add(ipToInt(lastBaseIP), big.NewInt(1))) > 0 {
+		return nil, fmt.Errorf("baseCidrRange %s is exhausted!", c.baseCidrRange)
+	}
+	return &net.IPNet{IP: intToIP(next, c.addressBits), Mask: net.CIDRMask(c.prefixLength, c.addressBits)}, nil
+}
+
 // This algorithm first tries to find the next subnet and fill "gaps" as good as possible. Therefore it starts to search at an already reserved subnet with equal or smaller prefix size. It afterwards continues with bigger prefix sizes.
-func (c cidrCalculator) recursivelyFindNextNetmask(ipNets *[]*net.IPNet, searchPrefixLength int8, doneWithBiggerEqualPrefix bool) (*net.IPNet, error) {
+func (c cidrCalculator) recursivelyFindNextNetmask(ipNets *[]*net.IPNet, searchPrefixLength int, doneWithBiggerEqualPrefix bool) (*net.IPNet, error) {
 	if searchPrefixLength <= c.baseCidrPrefixLength {
 		lastSubnet := (*ipNets)[0]
-		nextIPNet, exhausted := cidr.NextSubnet(lastSubnet, int(c.prefixLength))
+		nextIPNet, exhausted := cidr.NextSubnet(lastSubnet, c.prefixLength)
 		if exhausted {
 			return nil, fmt.Errorf("Maximum IP exhausted!")
 		}
 		return nextIPNet, nil
 	}
-	_, maskNet, _ := net.ParseCIDR(fmt.Sprintf("0.0.0.0/%d", searchPrefixLength))
-	mask := maskNet.Mask
+	mask := net.CIDRMask(searchPrefixLength, c.addressBits)
 	var previousRunSubnet *net.IPNet
 	for index, ipNet := range *ipNets {
 		compare := bytes.Compare(mask, ipNet.Mask)
@@ -114,7 +236,7 @@ func (c cidrCalculator) getNextSubnetVerifyNoOverlap(index int, ipNets *[]*net.I
 	if index > 0 {
 		previousSubnet = (*ipNets)[index-1]
 	}
-	calculatedNextSubnet, exhausted := cidr.NextSubnet((*ipNets)[index], int(c.prefixLength))
+	calculatedNextSubnet, exhausted := cidr.NextSubnet((*ipNets)[index], c.prefixLength)
 	if exhausted {
 		return nil, false, fmt.Errorf("Maximum IP exhausted!")
 	}
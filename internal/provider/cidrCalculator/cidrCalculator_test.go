@@ -7,7 +7,7 @@ import (
 
 type TestData struct {
 	currentSubnets *map[string]string
-	prefixLength   int8
+	prefixLength   int
 	baseCidrRange  string
 }
 
@@ -68,3 +68,124 @@ func TestBaseCidrExhausted(t *testing.T) {
 		t.Fatalf("The error message does not match: Expected %s, Got %s", expected, err.Error())
 	}
 }
+
+func TestCorrectNextCidrIPv6(t *testing.T) {
+	currentSubnets := &map[string]string{"nodes": "fd00:1::/56", "pods": "fd00:1:0:100::/64", "services": "fd00:1:0:101::/64"}
+	expected := map[int]string{64: "fd00:1:0:102::/64", 56: "fd00:1:0:200::/56", 48: ""}
+	for prefixLength, expectedNetmask := range expected {
+		theCidrCalculator, err := New(currentSubnets, prefixLength, "fd00:1::/48")
+		if err != nil {
+			t.Fatal(err)
+		}
+		netmask, err := theCidrCalculator.GetNextNetmask()
+		if expectedNetmask == "" {
+			if err == nil {
+				t.Fatalf("There should be an error when Cidr Range is exhausted, got %s", netmask)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if netmask != expectedNetmask {
+			t.Fatalf("Unexpected value for next netmask %s, expected %s", netmask, expectedNetmask)
+		}
+	}
+}
+
+func TestFirstCidrIPv6(t *testing.T) {
+	theCidrCalculator, err := New(&map[string]string{}, 64, "fd00:1::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	netmask, err := theCidrCalculator.GetNextNetmask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "fd00:1::/64" {
+		t.Fatalf("Unexpected value for next netmask %s", netmask)
+	}
+}
+
+func TestPrefixLengthOutOfRange(t *testing.T) {
+	for baseCidrRange, prefixLength := range map[string]int{"10.116.0.0/14": 33, "fd00:1::/48": 129} {
+		theCidrCalculator, err := New(&map[string]string{}, prefixLength, baseCidrRange)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := theCidrCalculator.GetNextNetmask(); err == nil {
+			t.Fatalf("prefixLength %d should be rejected for %s", prefixLength, baseCidrRange)
+		}
+	}
+}
+
+func TestMixedAddressFamilies(t *testing.T) {
+	theCidrCalculator, err := New(&map[string]string{"v4": "10.116.0.0/24"}, 64, "fd00:1::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := theCidrCalculator.GetNextNetmask(); err == nil {
+		t.Fatal("IPv4 subnets within an IPv6 base cidr range should be rejected")
+	}
+}
+
+func TestAllocationStrategies(t *testing.T) {
+	currentSubnets := &map[string]string{"a": "10.0.0.64/26", "b": "10.0.0.128/27", "c": "10.0.0.192/27"}
+	expected := map[Strategy]string{FirstFit: "10.0.0.0/27", BestFit: "10.0.0.160/27", Append: "10.0.0.224/27"}
+	for strategy, expectedNetmask := range expected {
+		theCidrCalculator, err := New(currentSubnets, 27, "10.0.0.0/24")
+		if err != nil {
+			t.Fatal(err)
+		}
+		netmask, err := theCidrCalculator.WithStrategy(strategy).GetNextNetmask()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if netmask != expectedNetmask {
+			t.Fatalf("Unexpected value for next netmask %s with strategy %s, expected %s", netmask, strategy, expectedNetmask)
+		}
+	}
+}
+
+func TestAppendNeverBackfills(t *testing.T) {
+	theCidrCalculator, err := New(&map[string]string{"a": "10.0.0.64/26", "b": "10.0.0.128/27", "c": "10.0.0.192/27"}, 26, "10.0.0.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask, err := theCidrCalculator.WithStrategy(Append).GetNextNetmask(); err == nil {
+		t.Fatalf("Append should not backfill 10.0.0.0/26, got %s", netmask)
+	}
+	netmask, err := theCidrCalculator.WithStrategy(FirstFit).GetNextNetmask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "10.0.0.0/26" {
+		t.Fatalf("Unexpected value for next netmask %s", netmask)
+	}
+}
+
+func TestFirstFitPicksLowestAddress(t *testing.T) {
+	testData := initTestData()
+	theCidrCalculator, err := New(testData.currentSubnets, 29, testData.baseCidrRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	netmask, err := theCidrCalculator.WithStrategy(FirstFit).GetNextNetmask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "10.116.0.0/29" {
+		t.Fatalf("Unexpected value for next netmask %s", netmask)
+	}
+	theCidrCalculator, err = New(&map[string]string{"nodes": "fd00:1::/64"}, 64, "fd00:1::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	netmask, err = theCidrCalculator.WithStrategy(FirstFit).GetNextNetmask()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "fd00:1:0:1::/64" {
+		t.Fatalf("Unexpected value for next netmask %s", netmask)
+	}
+}
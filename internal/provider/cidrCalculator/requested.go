@@ -0,0 +1,52 @@
+package cidrCalculator
+
+import (
+	"fmt"
+	"github.com/apparentlymart/go-cidr/cidr"
+	"net"
+	"sort"
+)
+
+// VerifyRequestedNetmask checks, that the requested subnet is a valid network address within baseCidrRange, which
+// does not overlap with any of the current subnets, given by their ids. It returns the requested subnet in its canonical form.
+func VerifyRequestedNetmask(currentSubnets *map[string]string, requested string, baseCidrRange string) (string, error) {
+	_, baseIPNet, err := net.ParseCIDR(baseCidrRange)
+	if err != nil {
+		return "", err
+	}
+	requestedIP, requestedIPNet, err := net.ParseCIDR(requested)
+	if err != nil {
+		return "", err
+	}
+	if !requestedIP.Equal(requestedIPNet.IP) {
+		return "", fmt.Errorf("The requested cidr %s is not a network address, did you mean %s?", requested, requestedIPNet.String())
+	}
+	basePrefixLength, addressBits := baseIPNet.Mask.Size()
+	requestedPrefixLength, requestedBits := requestedIPNet.Mask.Size()
+	if requestedBits != addressBits || requestedPrefixLength < basePrefixLength || !baseIPNet.Contains(requestedIPNet.IP) {
+		return "", fmt.Errorf("The requested cidr %s does not lie within baseCidrRange %s!", requested, baseCidrRange)
+	}
+	netmaskIds := make([]string, 0, len(*currentSubnets))
+	for netmaskId := range *currentSubnets {
+		netmaskIds = append(netmaskIds, netmaskId)
+	}
+	sort.Strings(netmaskIds)
+	ipNets := []*net.IPNet{requestedIPNet}
+	for _, netmaskId := range netmaskIds {
+		_, ipNet, err := net.ParseCIDR((*currentSubnets)[netmaskId])
+		if err != nil {
+			return "", err
+		}
+		if !baseIPNet.Contains(ipNet.IP) {
+			continue
+		}
+		if ipNet.Contains(requestedIPNet.IP) || requestedIPNet.Contains(ipNet.IP) {
+			return "", fmt.Errorf("The requested cidr %s conflicts with %s, which is already reserved by %s!", requested, ipNet.String(), netmaskId)
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	if err := cidr.VerifyNoOverlap(ipNets, baseIPNet); err != nil {
+		return "", fmt.Errorf("The requested cidr %s conflicts with existing subnets: %s", requested, err)
+	}
+	return requestedIPNet.String(), nil
+}
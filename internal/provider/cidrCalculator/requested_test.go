@@ -0,0 +1,35 @@
+package cidrCalculator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyRequestedNetmask(t *testing.T) {
+	testData := initTestData()
+	netmask, err := VerifyRequestedNetmask(testData.currentSubnets, "10.116.12.0/22", testData.baseCidrRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "10.116.12.0/22" {
+		t.Fatalf("Unexpected netmask %s", netmask)
+	}
+}
+
+func TestVerifyRequestedNetmaskConflicts(t *testing.T) {
+	testData := initTestData()
+	expectedErrors := map[string]string{
+		"10.116.8.0/24":  "already reserved by test5",
+		"10.116.0.0/16":  "already reserved by fioo",
+		"10.120.0.0/24":  "does not lie within baseCidrRange",
+		"10.112.0.0/12":  "does not lie within baseCidrRange",
+		"10.116.12.1/22": "is not a network address",
+		"fd00::/64":      "does not lie within baseCidrRange",
+	}
+	for requested, expected := range expectedErrors {
+		_, err := VerifyRequestedNetmask(testData.currentSubnets, requested, testData.baseCidrRange)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Requesting %s should fail with %s, got %v", requested, expected, err)
+		}
+	}
+}
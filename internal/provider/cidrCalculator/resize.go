@@ -0,0 +1,37 @@
+package cidrCalculator
+
+import (
+	"fmt"
+	"net"
+)
+
+// ResizeInPlace returns the subnet of prefixLength, which keeps the subnet of netmaskId in place: a smaller subnet
+// starts at the same address, a bigger one is the enclosing subnet of the new size, which starts at the same address
+// if the current subnet is aligned to the new size. It fails, if the bigger subnet leaves baseCidrRange or overlaps
+// any other of the current subnets.
+func ResizeInPlace(currentSubnets *map[string]string, netmaskId string, prefixLength int, baseCidrRange string) (string, error) {
+	current, contains := (*currentSubnets)[netmaskId]
+	if !contains {
+		return "", fmt.Errorf("Netmask with id %s does not exist!", netmaskId)
+	}
+	_, currentIPNet, err := net.ParseCIDR(current)
+	if err != nil {
+		return "", err
+	}
+	_, addressBits := currentIPNet.Mask.Size()
+	if prefixLength < 0 || prefixLength > addressBits {
+		return "", fmt.Errorf("prefixLength must be an integer between 0 and %d", addressBits)
+	}
+	mask := net.CIDRMask(prefixLength, addressBits)
+	resized := &net.IPNet{IP: currentIPNet.IP.Mask(mask), Mask: mask}
+	others := make(map[string]string, len(*currentSubnets))
+	for otherNetmaskId, subnet := range *currentSubnets {
+		if otherNetmaskId != netmaskId {
+			others[otherNetmaskId] = subnet
+		}
+	}
+	if _, err := VerifyRequestedNetmask(&others, resized.String(), baseCidrRange); err != nil {
+		return "", fmt.Errorf("%s cannot be resized in place to %s: %s", current, resized.String(), err)
+	}
+	return resized.String(), nil
+}
@@ -0,0 +1,54 @@
+package cidrCalculator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResizeInPlace(t *testing.T) {
+	currentSubnets := map[string]string{
+		"aligned":   "10.116.0.0/26",
+		"unaligned": "10.116.1.64/26",
+		"v6":        "fd00::/64",
+	}
+	expected := map[string]map[int]string{
+		"aligned":   {25: "10.116.0.0/25", 24: "10.116.0.0/24", 28: "10.116.0.0/28"},
+		"unaligned": {25: "10.116.1.0/25", 24: "10.116.1.0/24", 27: "10.116.1.64/27"},
+	}
+	for netmaskId, resizes := range expected {
+		for prefixLength, netmask := range resizes {
+			resized, err := ResizeInPlace(&currentSubnets, netmaskId, prefixLength, "10.116.0.0/14")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resized != netmask {
+				t.Fatalf("Resizing %s to /%d should result in %s, got %s", netmaskId, prefixLength, netmask, resized)
+			}
+		}
+	}
+	resized, err := ResizeInPlace(&currentSubnets, "v6", 56, "fd00::/48")
+	if err != nil || resized != "fd00::/56" {
+		t.Fatalf("Unexpected IPv6 resize %s, %v", resized, err)
+	}
+}
+
+func TestResizeInPlaceConflicts(t *testing.T) {
+	currentSubnets := map[string]string{
+		"neighbour": "10.116.2.64/26",
+		"blocker":   "10.116.2.0/26",
+	}
+	expectedErrors := map[int]string{
+		25: "already reserved by blocker",
+		13: "does not lie within baseCidrRange",
+		33: "prefixLength must be an integer between 0 and 32",
+	}
+	for prefixLength, expected := range expectedErrors {
+		_, err := ResizeInPlace(&currentSubnets, "neighbour", prefixLength, "10.116.0.0/14")
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Resizing to /%d should fail with %s, got %v", prefixLength, expected, err)
+		}
+	}
+	if _, err := ResizeInPlace(&currentSubnets, "missing", 24, "10.116.0.0/14"); err == nil {
+		t.Fatal("Resizing a missing netmask should fail")
+	}
+}
@@ -0,0 +1,158 @@
+package cidrCalculator
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/apparentlymart/go-cidr/cidr"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// Usage describes how much of a base cidr range is reserved and which blocks are still free.
+type Usage struct {
+	TotalAddresses *big.Int
+	UsedAddresses  *big.Int
+	// FreeNetmasks are the largest aligned blocks, which are not reserved, in ascending order
+	FreeNetmasks []string
+}
+
+func GetUsage(currentSubnets *map[string]string, baseCidrRange string) (Usage, error) {
+	_, baseIPNet, err := net.ParseCIDR(baseCidrRange)
+	if err != nil {
+		return Usage{}, err
+	}
+	ipNets, err := parseSubnets(currentSubnets, baseIPNet)
+	if err != nil {
+		return Usage{}, err
+	}
+	usage := Usage{TotalAddresses: addressCount(baseIPNet), UsedAddresses: big.NewInt(0)}
+	for _, ipNet := range mergeIPNets(ipNets) {
+		usage.UsedAddresses.Add(usage.UsedAddresses, addressCount(ipNet))
+	}
+	for _, free := range freeIPNets(baseIPNet, ipNets) {
+		usage.FreeNetmasks = append(usage.FreeNetmasks, free.String())
+	}
+	return usage, nil
+}
+
+// parseSubnets parses the reserved subnets within baseIPNet, sorted ascending by their address.
+func parseSubnets(currentSubnets *map[string]string, baseIPNet *net.IPNet) ([]*net.IPNet, error) {
+	_, addressBits := baseIPNet.Mask.Size()
+	ipNets := make([]*net.IPNet, 0, len(*currentSubnets))
+	for _, value := range *currentSubnets {
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, bits := ipNet.Mask.Size(); bits != addressBits {
+			return nil, fmt.Errorf("Subnet %s does not belong to the address family of baseCidrRange %s!", value, baseIPNet.String())
+		}
+		if baseIPNet.Contains(ipNet.IP) {
+			ipNets = append(ipNets, ipNet)
+		}
+	}
+	sort.Slice(ipNets, func(i, j int) bool {
+		return bytes.Compare(ipNets[i].IP, ipNets[j].IP) < 0
+	})
+	return ipNets, nil
+}
+
+// mergeIPNets drops subnets contained in others from the ascending sorted ipNets, so no address is counted twice.
+func mergeIPNets(ipNets []*net.IPNet) []*net.IPNet {
+	merged := make([]*net.IPNet, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		if len(merged) > 0 && merged[len(merged)-1].Contains(ipNet.IP) {
+			continue
+		}
+		merged = append(merged, ipNet)
+	}
+	return merged
+}
+
+// freeIPNets walks the gaps between the ascending sorted ipNets and splits each gap into the largest aligned blocks.
+func freeIPNets(baseIPNet *net.IPNet, ipNets []*net.IPNet) []*net.IPNet {
+	_, addressBits := baseIPNet.Mask.Size()
+	cursor := ipToInt(baseIPNet.IP)
+	_, lastIP := cidr.AddressRange(baseIPNet)
+	end := new(big.Int).Add(ipToInt(lastIP), big.NewInt(1))
+	var free []*net.IPNet
+	for _, ipNet := range ipNets {
+		start := ipToInt(ipNet.IP)
+		if start.Cmp(cursor) > 0 {
+			free = append(free, alignedBlocks(cursor, start, addressBits)...)
+		}
+		_, lastIP := cidr.AddressRange(ipNet)
+		if next := new(big.Int).Add(ipToInt(lastIP), big.NewInt(1)); next.Cmp(cursor) > 0 {
+			cursor = next
+		}
+	}
+	if end.Cmp(cursor) > 0 {
+		free = append(free, alignedBlocks(cursor, end, addressBits)...)
+	}
+	return free
+}
+
+// alignedBlocks splits the address range [from, to) into the largest blocks aligned to their own size.
+func alignedBlocks(from *big.Int, to *big.Int, addressBits int) []*net.IPNet {
+	var blocks []*net.IPNet
+	current := new(big.Int).Set(from)
+	for current.Cmp(to) < 0 {
+		hostBits := 0
+		for hostBits < addressBits {
+			size := new(big.Int).Lsh(big.NewInt(1), uint(hostBits+1))
+			aligned := new(big.Int).Mod(current, size).Sign() == 0
+			if !aligned || new(big.Int).Add(current, size).Cmp(to) > 0 {
+				break
+			}
+			hostBits++
+		}
+		blocks = append(blocks, &net.IPNet{IP: intToIP(current, addressBits), Mask: net.CIDRMask(addressBits-hostBits, addressBits)})
+		current.Add(current, new(big.Int).Lsh(big.NewInt(1), uint(hostBits)))
+	}
+	return blocks
+}
+
+// addressCount works like cidr.AddressCount, but does not overflow for large IPv6 ranges.
+func addressCount(ipNet *net.IPNet) *big.Int {
+	ones, bits := ipNet.Mask.Size()
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+}
+
+func ipToInt(ip net.IP) *big.Int {
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	return new(big.Int).SetBytes(ip)
+}
+
+func intToIP(value *big.Int, addressBits int) net.IP {
+	ip := make(net.IP, addressBits/8)
+	return value.FillBytes(ip)
+}
+
+// Uncovered returns the largest aligned blocks of cidrRange, which covering does not cover: none if covering contains
+// cidrRange, the rest of cidrRange if it contains covering, otherwise cidrRange itself.
+func Uncovered(cidrRange string, covering string) ([]string, error) {
+	_, ipNet, err := net.ParseCIDR(cidrRange)
+	if err != nil {
+		return nil, err
+	}
+	_, coveringIPNet, err := net.ParseCIDR(covering)
+	if err != nil {
+		return nil, err
+	}
+	ones, _ := ipNet.Mask.Size()
+	coveringOnes, _ := coveringIPNet.Mask.Size()
+	switch {
+	case coveringOnes <= ones && coveringIPNet.Contains(ipNet.IP):
+		return nil, nil
+	case coveringOnes > ones && ipNet.Contains(coveringIPNet.IP):
+		var uncovered []string
+		for _, free := range freeIPNets(ipNet, []*net.IPNet{coveringIPNet}) {
+			uncovered = append(uncovered, free.String())
+		}
+		return uncovered, nil
+	}
+	return []string{ipNet.String()}, nil
+}
@@ -0,0 +1,63 @@
+package cidrCalculator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	testData := initTestData()
+	usage, err := GetUsage(testData.currentSubnets, testData.baseCidrRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalAddresses.String() != "262144" || usage.UsedAddresses.String() != "68384" {
+		t.Fatalf("Unexpected address counts total %s, used %s", usage.TotalAddresses, usage.UsedAddresses)
+	}
+	expected := []string{"10.116.0.0/29", "10.116.0.24/29", "10.116.0.48/28", "10.116.0.64/26", "10.116.0.128/25", "10.116.12.0/22", "10.116.16.0/20", "10.116.32.0/19", "10.116.64.0/18", "10.116.128.0/17", "10.117.0.0/16", "10.118.0.0/16"}
+	if !reflect.DeepEqual(usage.FreeNetmasks, expected) {
+		t.Fatalf("Unexpected free netmasks %v", usage.FreeNetmasks)
+	}
+}
+
+func TestUsageIPv6(t *testing.T) {
+	usage, err := GetUsage(&map[string]string{"nodes": "fd00:1::/56"}, "fd00:1::/48")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.TotalAddresses.String() != "1208925819614629174706176" || usage.UsedAddresses.String() != "4722366482869645213696" {
+		t.Fatalf("Unexpected address counts total %s, used %s", usage.TotalAddresses, usage.UsedAddresses)
+	}
+	expected := []string{"fd00:1:0:100::/56", "fd00:1:0:200::/55", "fd00:1:0:400::/54", "fd00:1:0:800::/53", "fd00:1:0:1000::/52", "fd00:1:0:2000::/51", "fd00:1:0:4000::/50", "fd00:1:0:8000::/49"}
+	if !reflect.DeepEqual(usage.FreeNetmasks, expected) {
+		t.Fatalf("Unexpected free netmasks %v", usage.FreeNetmasks)
+	}
+}
+
+func TestUsageEmptyBaseCidr(t *testing.T) {
+	usage, err := GetUsage(&map[string]string{}, "10.5.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.UsedAddresses.Sign() != 0 || !reflect.DeepEqual(usage.FreeNetmasks, []string{"10.5.0.0/16"}) {
+		t.Fatalf("Unexpected usage %v", usage)
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	expected := map[[2]string][]string{
+		{"10.116.1.0/24", "10.116.0.0/23"}:  nil,
+		{"10.116.1.0/24", "10.116.1.0/24"}:  nil,
+		{"10.116.1.0/24", "10.116.1.64/26"}: {"10.116.1.0/26", "10.116.1.128/25"},
+		{"10.116.1.0/24", "10.116.2.0/24"}:  {"10.116.1.0/24"},
+	}
+	for ranges, uncovered := range expected {
+		actual, err := Uncovered(ranges[0], ranges[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, uncovered) {
+			t.Fatalf("Expected %v of %s to be uncovered by %s, got %v", uncovered, ranges[0], ranges[1], actual)
+		}
+	}
+}
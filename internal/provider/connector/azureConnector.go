@@ -0,0 +1,140 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+)
+
+// AzureConnector keeps the reservation documents in an Azure Blob Storage container. The ETag of the blob serves as
+// version token, writes are conditional on it with If-Match, or with If-None-Match when the blob does not exist yet.
+type AzureConnector struct {
+	ContainerName string
+	BaseCidrRange string
+	FileName      string
+	etag          *azcore.ETag
+	client        *azblob.Client
+}
+
+// AzureOptions configures the Azure Blob Storage client. Either ConnectionString (e.g. for Azurite) or AccountUrl
+// must be set; with AccountUrl the credentials are taken from the default Azure credential chain.
+type AzureOptions struct {
+	ConnectionString string
+	AccountUrl       string
+}
+
+var _ Connector = (*AzureConnector)(nil)
+var _ Lister = (*AzureConnector)(nil)
+
+func NewAzure(client *azblob.Client, containerName string, baseCidr string) AzureConnector {
+	return AzureConnector{containerName, baseCidr, DocumentName(baseCidr), nil, client}
+}
+
+// NewAzureFactory returns a Factory creating AzureConnectors for the given container, which share one client.
+func NewAzureFactory(containerName string, options AzureOptions) (Factory, error) {
+	client, err := NewAzureClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return func(baseCidr string) Connector {
+		azureConnector := NewAzure(client, containerName, baseCidr)
+		return &azureConnector
+	}, nil
+}
+
+func NewAzureClient(options AzureOptions) (*azblob.Client, error) {
+	if options.ConnectionString != "" {
+		return azblob.NewClientFromConnectionString(options.ConnectionString, nil)
+	}
+	if options.AccountUrl == "" {
+		return nil, fmt.Errorf("Either the connection string or the account url of the storage account must be set!")
+	}
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	return azblob.NewClient(options.AccountUrl, credential, nil)
+}
+
+func (az *AzureConnector) GetBaseCidrRange() string {
+	return az.BaseCidrRange
+}
+
+func (az *AzureConnector) GetLocation() string {
+	return az.ContainerName
+}
+
+func (az *AzureConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	var names []string
+	prefix := documentPrefix
+	pager := az.client.NewListBlobsFlatPager(az.ContainerName, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name != nil {
+				names = append(names, *item.Name)
+			}
+		}
+	}
+	return baseCidrsOfDocuments(names), nil
+}
+
+func (az *AzureConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	networkConfig := NetworkConfig{}
+	response, err := az.client.DownloadStream(ctx, az.ContainerName, az.FileName, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		az.etag = nil
+		return &networkConfig, ErrNotExist
+	}
+	if err != nil {
+		return &networkConfig, err
+	}
+	defer response.Body.Close()
+	slurp, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &networkConfig, err
+	}
+	az.etag = response.ETag
+	if err := json.Unmarshal(slurp, &networkConfig); err != nil {
+		return &networkConfig, err
+	}
+	return &networkConfig, nil
+}
+
+func (az *AzureConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return err
+	}
+	conditions := &blob.ModifiedAccessConditions{}
+	if az.etag == nil {
+		etagAny := azcore.ETagAny
+		conditions.IfNoneMatch = &etagAny
+	} else {
+		conditions.IfMatch = az.etag
+	}
+	contentType := "application/json"
+	response, err := az.client.UploadBuffer(ctx, az.ContainerName, az.FileName, marshalled, &azblob.UploadBufferOptions{
+		HTTPHeaders:      &blob.HTTPHeaders{BlobContentType: &contentType},
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+	})
+	if err != nil {
+		tflog.Error(ctx, "Failed to write file to Azure", map[string]interface{}{"error": err, "etag": az.etag})
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return fmt.Errorf("%w: %s", ErrConflict, err)
+		}
+		return err
+	}
+	az.etag = response.ETag
+	return nil
+}
@@ -0,0 +1,34 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// Runs against an Azure storage account, e.g. a local Azurite with its well-known development account:
+//
+//	docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0
+//	CIDR_RESERVATOR_AZURE_CONNECTION_STRING="UseDevelopmentStorage=true" go test ./...
+func TestAzureConnectorConformance(t *testing.T) {
+	connectionString := os.Getenv("CIDR_RESERVATOR_AZURE_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("CIDR_RESERVATOR_AZURE_CONNECTION_STRING is not set")
+	}
+	options := AzureOptions{ConnectionString: connectionString}
+	client, err := NewAzureClient(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	containerName := fmt.Sprintf("cidr-reservator-test-%d", time.Now().UnixNano())
+	if _, err := client.CreateContainer(context.Background(), containerName, nil); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := NewAzureFactory(containerName, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testConnectorConformance(t, factory)
+}
@@ -0,0 +1,27 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// VerifyChildPool checks for the reservation document of baseCidr, if it is a child pool, that it has not been released
+// and still matches the reservation of its parent, which is read by a connector of newConnector.
+func (networkConfig *NetworkConfig) VerifyChildPool(ctx context.Context, newConnector Factory, baseCidr string) error {
+	parent := networkConfig.Parent
+	if parent == nil {
+		return nil
+	}
+	if parent.Released {
+		return fmt.Errorf("The child pool %s has been released from the reservation %s of %s!", baseCidr, parent.NetmaskId, parent.BaseCidr)
+	}
+	parentConfig, err := newConnector(parent.BaseCidr).ReadRemote(ctx)
+	if err != nil && !errors.Is(err, ErrNotExist) {
+		return err
+	}
+	if parentConfig.Subnets[parent.NetmaskId] != baseCidr || parentConfig.ChildPools[parent.NetmaskId] != baseCidr {
+		return fmt.Errorf("The child pool %s does not match the reservation %s of %s anymore!", baseCidr, parent.NetmaskId, parent.BaseCidr)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ErrNotExist is returned by ReadRemote, if there is no reservation document for the base cidr range yet.
+var ErrNotExist = errors.New("reservation document does not exist")
+
+// ErrConflict is returned by WriteRemote, if the reservation document was modified since it has been read.
+var ErrConflict = errors.New("reservation document was modified concurrently")
+
+type NetworkConfig struct {
+	// SchemaVersion is the version of the document format, see Migrate. Documents written before it has been
+	// introduced do not have it.
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	Subnets       map[string]string `json:"subnets"`
+	// Exclusions are ranges by their exclusion id, which must never be handed out, e.g. on-prem or VPN transit ranges.
+	Exclusions map[string]string `json:"exclusions,omitempty"`
+	// Metadata describes the reserved subnets by their netmask id. Reservations without any metadata are left out.
+	Metadata map[string]ReservationMetadata `json:"metadata,omitempty"`
+	// ChildPools are the netmask ids of reservations promoted into child pools, with the reserved netmask, which is the
+	// base cidr range of the child pool.
+	ChildPools map[string]string `json:"child_pools,omitempty"`
+	// Parent is set, if the base cidr range is a child pool carved out of a reservation of another base cidr range.
+	Parent *ParentReservation `json:"parent,omitempty"`
+	// History lists the changes of the subnets made by Update, oldest first. Only the latest HistoryLimit ones are kept.
+	History []AuditEvent `json:"history,omitempty"`
+	// HistoryLimit is the number of events kept in the History, DefaultHistoryLimit if 0.
+	HistoryLimit int `json:"history_limit,omitempty"`
+	// QuarantinePeriod is a duration as understood by time.ParseDuration. If set, released subnets are kept as
+	// Tombstones for this long, before they can be handed out again.
+	QuarantinePeriod string      `json:"quarantine_period,omitempty"`
+	Tombstones       []Tombstone `json:"tombstones,omitempty"`
+	// Leases are the expiries of time-limited reservations by their netmask id. Expired ones are reclaimed by Update.
+	Leases map[string]Lease `json:"leases,omitempty"`
+	// reclaimed are the netmask ids, whose leases have been reclaimed by the running Update.
+	reclaimed map[string]bool
+}
+
+// ReservationMetadata tells who owns a reservation and why it exists.
+type ReservationMetadata struct {
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// SetMetadata stores the metadata of netmaskId, or removes it if it is empty.
+func (networkConfig *NetworkConfig) SetMetadata(netmaskId string, metadata ReservationMetadata) {
+	if metadata.Description == "" && metadata.Owner == "" && len(metadata.Labels) == 0 {
+		delete(networkConfig.Metadata, netmaskId)
+		return
+	}
+	if networkConfig.Metadata == nil {
+		networkConfig.Metadata = make(map[string]ReservationMetadata)
+	}
+	networkConfig.Metadata[netmaskId] = metadata
+}
+
+// ParentReservation refers to the reservation a child pool has been promoted from.
+type ParentReservation struct {
+	BaseCidr  string `json:"base_cidr"`
+	NetmaskId string `json:"netmask_id"`
+	// Released is set, once the parent reservation is no longer a child pool. Nothing may be reserved in the child
+	// pool from then on.
+	Released bool `json:"released,omitempty"`
+}
+
+// ExclusionPrefix marks exclusions within the map returned by OccupiedSubnets.
+const ExclusionPrefix = "exclusion/"
+
+// TombstonePrefix marks quarantined ranges within the map returned by OccupiedSubnets.
+const TombstonePrefix = "tombstone/"
+
+// OccupiedSubnets returns all ranges, which must not be handed out: the reserved subnets by their netmask id, the
+// exclusions by their exclusion id prefixed with ExclusionPrefix and the quarantined ranges, which are still in their
+// quarantine period, by their cidr range prefixed with TombstonePrefix.
+func (networkConfig *NetworkConfig) OccupiedSubnets() map[string]string {
+	return networkConfig.occupiedSubnets(networkConfig.ActiveTombstones())
+}
+
+// occupiedSubnets works like OccupiedSubnets, but only with the given tombstones.
+func (networkConfig *NetworkConfig) occupiedSubnets(tombstones []Tombstone) map[string]string {
+	occupied := make(map[string]string, len(networkConfig.Subnets)+len(networkConfig.Exclusions)+len(tombstones))
+	for _, tombstone := range tombstones {
+		occupied[TombstonePrefix+tombstone.Cidr] = tombstone.Cidr
+	}
+	for netmaskId, subnet := range networkConfig.Subnets {
+		occupied[netmaskId] = subnet
+	}
+	for exclusionId, excluded := range networkConfig.Exclusions {
+		occupied[ExclusionPrefix+exclusionId] = excluded
+	}
+	return occupied
+}
+
+// Connector loads the reservation document of one base cidr range together with its version token
+// and stores it again, but only if nobody else modified it in the meantime.
+type Connector interface {
+	// ReadRemote reads the reservation document and remembers its version token for the next WriteRemote.
+	ReadRemote(ctx context.Context) (*NetworkConfig, error)
+	// WriteRemote stores the reservation document, if the remembered version token is still the current one.
+	// Without a preceding ReadRemote the document must not exist yet.
+	WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error
+	// GetBaseCidrRange returns the base cidr range the reservation document belongs to.
+	GetBaseCidrRange() string
+	// GetLocation returns the name of the storage location (e.g. the bucket) holding the reservation document.
+	GetLocation() string
+}
+
+// TransactionalConnector is implemented by connectors backed by a database, which modify the reservation document
+// within a transaction instead of rewriting it as a whole.
+type TransactionalConnector interface {
+	Connector
+	// Transaction reads the reservation document, applies modify to it and stores the changes within one transaction.
+	// A missing document is passed to modify as an empty one. Conflicting transactions fail with ErrConflict.
+	Transaction(ctx context.Context, modify func(networkConfig *NetworkConfig) error) error
+}
+
+// Update reads the reservation document, applies modify to it and writes it back, if nobody else modified it in the
+// meantime. A missing document is passed to modify as an empty one. TransactionalConnectors run modify within their
+// transaction. The document is migrated to CurrentSchemaVersion before modify; documents written by a newer version of
+// the provider fail with ErrNewerSchemaVersion, as their unknown content would be lost. The changes of the subnets are
+// appended to the history on behalf of the caller set by WithCaller, and released subnets are quarantined.
+func Update(ctx context.Context, remote Connector, modify func(networkConfig *NetworkConfig) error) error {
+	modify = migrateBefore(recordChanges(ctx, modify))
+	if transactional, ok := remote.(TransactionalConnector); ok {
+		return transactional.Transaction(ctx, modify)
+	}
+	networkConfig, err := readOrEmpty(ctx, remote)
+	if err != nil {
+		return err
+	}
+	if err := modify(networkConfig); err != nil {
+		return err
+	}
+	return remote.WriteRemote(networkConfig, ctx)
+}
+
+// Retry runs toRetry up to 4 times, until it succeeds or fails with an error, which is not retryable.
+func Retry(toRetry func() error, retryable func(err error) bool) error {
+	var err error
+	for attempt := 0; attempt < 4; attempt++ {
+		err = toRetry()
+		if err == nil || !retryable(err) {
+			break
+		}
+	}
+	return err
+}
+
+// Retryable tells, whether err may go away by trying again: concurrent modifications, failures to reach the backend
+// and server errors of reservation services. Requests rejected by a reservation service are never retried.
+func Retryable(err error) bool {
+	var statusErr statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= 500
+	}
+	var netErr net.Error
+	return errors.Is(err, ErrConflict) || errors.As(err, &netErr)
+}
+
+// DryRun applies modify to the current reservation document just like Update, but never stores the result, e.g. to
+// preview the outcome of a change at plan time.
+func DryRun(ctx context.Context, remote Connector, modify func(networkConfig *NetworkConfig) error) error {
+	networkConfig, err := readOrEmpty(ctx, remote)
+	if err != nil {
+		return err
+	}
+	return migrateBefore(recordChanges(ctx, modify))(networkConfig)
+}
+
+// readOrEmpty reads the reservation document of remote, or returns an empty one if there is none yet.
+func readOrEmpty(ctx context.Context, remote Connector) (*NetworkConfig, error) {
+	networkConfig, err := remote.ReadRemote(ctx)
+	if errors.Is(err, ErrNotExist) {
+		return &NetworkConfig{Subnets: make(map[string]string)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if networkConfig.Subnets == nil {
+		networkConfig.Subnets = make(map[string]string)
+	}
+	return networkConfig, nil
+}
+
+// Factory creates the Connector for the reservation document of a base cidr range.
+type Factory func(baseCidr string) Connector
+
+// documentPrefix is the common prefix of the names of all reservation documents.
+const documentPrefix = "cidr-reservation/baseCidr-"
+
+// DocumentName returns the name under which the reservation document of baseCidr is stored.
+func DocumentName(baseCidr string) string {
+	return fmt.Sprintf("%s%s.json", documentPrefix, strings.NewReplacer(".", "-", "/", "-", ":", "-").Replace(baseCidr))
+}
@@ -0,0 +1,59 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testConnectorConformance verifies the conditional read/write semantics every Connector has to provide.
+// newConnector must return connectors sharing the same, initially empty, storage location.
+func testConnectorConformance(t *testing.T, newConnector Factory) {
+	ctx := context.Background()
+	baseCidr := "10.116.0.0/14"
+
+	first := newConnector(baseCidr)
+	if _, err := first.ReadRemote(ctx); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("Reading a missing document should return ErrNotExist, got %v", err)
+	}
+	if err := first.WriteRemote(&NetworkConfig{Subnets: map[string]string{"first": "10.116.0.0/24"}}, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := newConnector(baseCidr).WriteRemote(&NetworkConfig{Subnets: map[string]string{}}, ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Creating an already existing document should return ErrConflict, got %v", err)
+	}
+
+	second := newConnector(baseCidr)
+	networkConfig, err := second.ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkConfig.Subnets["first"] != "10.116.0.0/24" {
+		t.Fatalf("Unexpected document content %v", networkConfig.Subnets)
+	}
+	if _, err := first.ReadRemote(ctx); err != nil {
+		t.Fatal(err)
+	}
+	networkConfig.Subnets["second"] = "10.116.1.0/24"
+	if err := second.WriteRemote(networkConfig, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.WriteRemote(&NetworkConfig{Subnets: map[string]string{}}, ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Writing a stale document should return ErrConflict, got %v", err)
+	}
+	networkConfig.Subnets["third"] = "10.116.2.0/24"
+	if err := second.WriteRemote(networkConfig, ctx); err != nil {
+		t.Fatalf("Writing twice without reading in between should succeed for the writer, got %v", err)
+	}
+
+	networkConfig, err = newConnector(baseCidr).ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Subnets) != 3 {
+		t.Fatalf("Unexpected document content %v", networkConfig.Subnets)
+	}
+	if _, err := newConnector("10.5.0.0/16").ReadRemote(ctx); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("Documents of different base cidr ranges must be independent, got %v", err)
+	}
+}
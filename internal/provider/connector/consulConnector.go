@@ -0,0 +1,109 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"path"
+)
+
+// ConsulConnector keeps the reservation documents in the Consul KV store. The ModifyIndex of the key serves as version
+// token, writes are check-and-set operations on it; an index of 0 only succeeds when the key does not exist yet.
+type ConsulConnector struct {
+	KeyPrefix     string
+	BaseCidrRange string
+	Key           string
+	modifyIndex   uint64
+	kv            *api.KV
+}
+
+// ConsulOptions configures the Consul client; unset values are taken from the CONSUL_HTTP_* environment variables.
+type ConsulOptions struct {
+	Address string
+	Token   string
+}
+
+var _ Connector = (*ConsulConnector)(nil)
+var _ Lister = (*ConsulConnector)(nil)
+
+func NewConsul(client *api.Client, keyPrefix string, baseCidr string) ConsulConnector {
+	return ConsulConnector{keyPrefix, baseCidr, path.Join(keyPrefix, DocumentName(baseCidr)), 0, client.KV()}
+}
+
+// NewConsulFactory returns a Factory creating ConsulConnectors for keys below keyPrefix, which share one client.
+func NewConsulFactory(keyPrefix string, options ConsulOptions) (Factory, error) {
+	client, err := NewConsulClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return func(baseCidr string) Connector {
+		consulConnector := NewConsul(client, keyPrefix, baseCidr)
+		return &consulConnector
+	}, nil
+}
+
+func NewConsulClient(options ConsulOptions) (*api.Client, error) {
+	consulConfig := api.DefaultConfig()
+	if options.Address != "" {
+		consulConfig.Address = options.Address
+	}
+	if options.Token != "" {
+		consulConfig.Token = options.Token
+	}
+	return api.NewClient(consulConfig)
+}
+
+func (consul *ConsulConnector) GetBaseCidrRange() string {
+	return consul.BaseCidrRange
+}
+
+func (consul *ConsulConnector) GetLocation() string {
+	return consul.KeyPrefix
+}
+
+func (consul *ConsulConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	names, _, err := consul.kv.Keys(path.Join(consul.KeyPrefix, documentPrefix), "", (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return baseCidrsOfDocuments(names), nil
+}
+
+func (consul *ConsulConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	networkConfig := NetworkConfig{}
+	pair, _, err := consul.kv.Get(consul.Key, (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil {
+		return &networkConfig, err
+	}
+	if pair == nil {
+		consul.modifyIndex = 0
+		return &networkConfig, ErrNotExist
+	}
+	consul.modifyIndex = pair.ModifyIndex
+	if err := json.Unmarshal(pair.Value, &networkConfig); err != nil {
+		return &networkConfig, err
+	}
+	return &networkConfig, nil
+}
+
+func (consul *ConsulConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return err
+	}
+	// a transaction is used instead of a plain CAS, as it reports the new ModifyIndex of the key
+	ok, response, _, err := consul.kv.Txn(api.KVTxnOps{
+		{Verb: api.KVCAS, Key: consul.Key, Value: marshalled, Index: consul.modifyIndex},
+	}, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		tflog.Error(ctx, "Failed to write key to Consul", map[string]interface{}{"errors": response.Errors, "modifyIndex": consul.modifyIndex})
+		return fmt.Errorf("%w: check-and-set on %s with index %d failed", ErrConflict, consul.Key, consul.modifyIndex)
+	}
+	consul.modifyIndex = response.Results[0].ModifyIndex
+	return nil
+}
@@ -0,0 +1,23 @@
+package connector
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// Runs against a Consul agent, e.g. a local one in dev mode:
+//
+//	consul agent -dev
+//	CONSUL_HTTP_ADDR=127.0.0.1:8500 go test ./...
+func TestConsulConnectorConformance(t *testing.T) {
+	if os.Getenv("CONSUL_HTTP_ADDR") == "" {
+		t.Skip("CONSUL_HTTP_ADDR is not set")
+	}
+	factory, err := NewConsulFactory(fmt.Sprintf("cidr-reservator-test-%d", time.Now().UnixNano()), ConsulOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testConnectorConformance(t, factory)
+}
@@ -0,0 +1,59 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Backends lists the storage backends supported by NewFactory.
+var Backends = []string{"gcs", "local", "s3", "azure", "consul", "postgres", "http"}
+
+// Options select the storage backend and configure it. Only the settings of the selected Backend are used.
+type Options struct {
+	Backend string
+	// Bucket is the bucket of the gcs and s3 backends and the container of the azure backend.
+	Bucket                   string
+	LocalDirectory           string
+	S3                       S3Options
+	Azure                    AzureOptions
+	ConsulKeyPrefix          string
+	Consul                   ConsulOptions
+	PostgresConnectionString string
+	Http                     HttpOptions
+}
+
+// NewFactory returns the Factory of the backend selected by options.
+func NewFactory(ctx context.Context, options Options) (Factory, error) {
+	switch options.Backend {
+	case "gcs":
+		if options.Bucket == "" {
+			return nil, fmt.Errorf("The gcs backend requires a bucket!")
+		}
+		return NewGcpFactory(options.Bucket), nil
+	case "local":
+		if options.LocalDirectory == "" {
+			return nil, fmt.Errorf("The local backend requires a directory!")
+		}
+		return NewLocalFactory(options.LocalDirectory), nil
+	case "s3":
+		if options.Bucket == "" {
+			return nil, fmt.Errorf("The s3 backend requires a bucket!")
+		}
+		return NewS3Factory(ctx, options.Bucket, options.S3)
+	case "azure":
+		if options.Bucket == "" {
+			return nil, fmt.Errorf("The azure backend requires a container!")
+		}
+		return NewAzureFactory(options.Bucket, options.Azure)
+	case "consul":
+		return NewConsulFactory(options.ConsulKeyPrefix, options.Consul)
+	case "postgres":
+		return NewPostgresFactory(ctx, options.PostgresConnectionString)
+	case "http":
+		return NewHttpFactory(options.Http, &http.Client{Timeout: 30 * time.Second})
+	default:
+		return nil, fmt.Errorf("backend %s is not supported!", options.Backend)
+	}
+}
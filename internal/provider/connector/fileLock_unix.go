@@ -0,0 +1,20 @@
+//go:build !windows
+
+package connector
+
+import (
+	"os"
+	"syscall"
+)
+
+func acquireFileLock(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(file.Fd()), how)
+}
+
+func releaseFileLock(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -7,8 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"io"
-	"strings"
+	"net/http"
 	"time"
 )
 
@@ -19,13 +21,48 @@ type GcpConnector struct {
 	generation    int64
 }
 
-type NetworkConfig struct {
-	Subnets map[string]string `json:"subnets"`
-}
+var _ Connector = (*GcpConnector)(nil)
+var _ Lister = (*GcpConnector)(nil)
 
 func New(bucketName string, baseCidr string) GcpConnector {
-	fileName := fmt.Sprintf("cidr-reservation/baseCidr-%s.json", strings.Replace(strings.Replace(baseCidr, ".", "-", -1), "/", "-", -1))
-	return GcpConnector{bucketName, baseCidr, fileName, -1}
+	return GcpConnector{bucketName, baseCidr, DocumentName(baseCidr), -1}
+}
+
+// NewGcpFactory returns a Factory creating GcpConnectors for the given bucket.
+func NewGcpFactory(bucketName string) Factory {
+	return func(baseCidr string) Connector {
+		gcpConnector := New(bucketName, baseCidr)
+		return &gcpConnector
+	}
+}
+
+func (gcp *GcpConnector) GetBaseCidrRange() string {
+	return gcp.BaseCidrRange
+}
+
+func (gcp *GcpConnector) GetLocation() string {
+	return gcp.BucketName
+}
+
+func (gcp *GcpConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	var names []string
+	objects := client.Bucket(gcp.BucketName).Objects(ctx, &storage.Query{Prefix: documentPrefix})
+	for {
+		attrs, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, attrs.Name)
+	}
+	return baseCidrsOfDocuments(names), nil
 }
 
 func (gcp *GcpConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
@@ -48,6 +85,9 @@ func (gcp *GcpConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error)
 		gcp.generation = attrs.Generation
 	}
 	rc, err := objectHandle.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return &networkConfig, ErrNotExist
+	}
 	if err != nil {
 		return &networkConfig, err
 	}
@@ -84,6 +124,10 @@ func (gcp *GcpConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.C
 	_, _ = writer.Write(marshalled)
 	if err := writer.Close(); err != nil {
 		tflog.Error(ctx, "Failed to write file to GCP", map[string]interface{}{"error": err, "generation": gcp.generation})
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return fmt.Errorf("%w: %s", ErrConflict, err)
+		}
 		return err
 	}
 	return nil
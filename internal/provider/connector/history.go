@@ -0,0 +1,152 @@
+package connector
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+)
+
+// AuditEvent records a change of a reservation. Events are only ever appended to the history of a reservation
+// document, within the same write as the change itself. The oldest ones are dropped beyond its history limit.
+type AuditEvent struct {
+	Timestamp string `json:"timestamp"`
+	// Action is one of "allocate", "reallocate", "rename", "release" and "expire".
+	Action    string `json:"action"`
+	NetmaskId string `json:"netmask_id"`
+	// OldNetmaskId is only set for renames.
+	OldNetmaskId string `json:"old_netmask_id,omitempty"`
+	OldCidr      string `json:"old_cidr,omitempty"`
+	NewCidr      string `json:"new_cidr,omitempty"`
+	Caller       string `json:"caller,omitempty"`
+}
+
+// DefaultCaller identifies the caller by the user and host running the process.
+func DefaultCaller() string {
+	username := "unknown"
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return username
+	}
+	return username + "@" + hostname
+}
+
+type callerKey struct{}
+
+// WithCaller returns a context, whose changes made by Update are recorded in the history on behalf of caller.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerOf(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// DefaultHistoryLimit is the number of events kept in the history of reservation documents without history limit. The
+// whole document is rewritten by every change, so an unbounded history would slow down writes as the pool ages.
+const DefaultHistoryLimit = 1000
+
+// now is replaced by tests to get reproducible timestamps.
+var now = time.Now
+
+// recordChanges wraps modify, so expired leases are reclaimed before, the changes to the subnets are appended to the
+// history, which is trimmed to its limit, and the released subnets are quarantined. Whatever modify does to the history
+// itself is discarded.
+func recordChanges(ctx context.Context, modify func(networkConfig *NetworkConfig) error) func(networkConfig *NetworkConfig) error {
+	return func(networkConfig *NetworkConfig) error {
+		history := networkConfig.History
+		before := make(map[string]string, len(networkConfig.Subnets))
+		for netmaskId, subnet := range networkConfig.Subnets {
+			before[netmaskId] = subnet
+		}
+		reclaimed := networkConfig.reclaimExpiredLeases()
+		if err := modify(networkConfig); err != nil {
+			return err
+		}
+		timestamp := now().UTC()
+		events := auditEvents(before, networkConfig.Subnets, timestamp.Format(time.RFC3339), callerOf(ctx))
+		for i := range events {
+			if events[i].Action == "release" && reclaimed[events[i].NetmaskId] {
+				events[i].Action = "expire"
+			}
+		}
+		networkConfig.History = append(history[:len(history):len(history)], events...)
+		limit := networkConfig.HistoryLimit
+		if limit <= 0 {
+			limit = DefaultHistoryLimit
+		}
+		if len(networkConfig.History) > limit {
+			networkConfig.History = networkConfig.History[len(networkConfig.History)-limit:]
+		}
+		return networkConfig.quarantine(events, timestamp)
+	}
+}
+
+// auditEvents returns the events turning the subnets before into the ones after, ordered by netmask id. A released
+// and an allocated netmask id with the same cidr range are recorded as rename.
+func auditEvents(before map[string]string, after map[string]string, timestamp string, caller string) []AuditEvent {
+	released := make(map[string]string)
+	for netmaskId, subnet := range before {
+		if _, contains := after[netmaskId]; !contains {
+			released[subnet] = netmaskId
+		}
+	}
+	var events []AuditEvent
+	for netmaskId, subnet := range after {
+		event := AuditEvent{Timestamp: timestamp, NetmaskId: netmaskId, NewCidr: subnet, Caller: caller}
+		if oldSubnet, contains := before[netmaskId]; !contains {
+			if oldNetmaskId, renamed := released[subnet]; renamed {
+				event.Action = "rename"
+				event.OldNetmaskId = oldNetmaskId
+				event.OldCidr = subnet
+				delete(released, subnet)
+			} else {
+				event.Action = "allocate"
+			}
+		} else if oldSubnet != subnet {
+			event.Action = "reallocate"
+			event.OldCidr = oldSubnet
+		} else {
+			continue
+		}
+		events = append(events, event)
+	}
+	for subnet, netmaskId := range released {
+		events = append(events, AuditEvent{Timestamp: timestamp, Action: "release", NetmaskId: netmaskId, OldCidr: subnet, Caller: caller})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].NetmaskId < events[j].NetmaskId
+	})
+	return events
+}
+
+// FilterHistory returns the events of netmaskId, including its renames, and of the cidr ranges overlapping cidr. An
+// empty netmaskId or a nil cidr matches all events.
+func FilterHistory(events []AuditEvent, netmaskId string, cidr *net.IPNet) []AuditEvent {
+	filtered := make([]AuditEvent, 0)
+	for _, event := range events {
+		if netmaskId != "" && event.NetmaskId != netmaskId && event.OldNetmaskId != netmaskId {
+			continue
+		}
+		if cidr != nil && !overlaps(cidr, event.OldCidr) && !overlaps(cidr, event.NewCidr) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	return filtered
+}
+
+// overlaps tells whether the cidr range subnet shares any address with ipNet.
+func overlaps(ipNet *net.IPNet, subnet string) bool {
+	_, subnetIPNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	return ipNet.Contains(subnetIPNet.IP) || subnetIPNet.Contains(ipNet.IP)
+}
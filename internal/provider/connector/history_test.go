@@ -0,0 +1,94 @@
+package connector
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAuditEvents(t *testing.T) {
+	before := map[string]string{"kept": "10.116.0.0/24", "resized": "10.116.1.0/24", "renamed": "10.116.2.0/24", "released": "10.116.3.0/24"}
+	after := map[string]string{"kept": "10.116.0.0/24", "resized": "10.116.4.0/23", "new-name": "10.116.2.0/24", "allocated": "10.116.6.0/24"}
+	expected := []AuditEvent{
+		{Timestamp: "now", Action: "allocate", NetmaskId: "allocated", NewCidr: "10.116.6.0/24", Caller: "alice"},
+		{Timestamp: "now", Action: "rename", NetmaskId: "new-name", OldNetmaskId: "renamed", OldCidr: "10.116.2.0/24", NewCidr: "10.116.2.0/24", Caller: "alice"},
+		{Timestamp: "now", Action: "release", NetmaskId: "released", OldCidr: "10.116.3.0/24", Caller: "alice"},
+		{Timestamp: "now", Action: "reallocate", NetmaskId: "resized", OldCidr: "10.116.1.0/24", NewCidr: "10.116.4.0/23", Caller: "alice"},
+	}
+	if events := auditEvents(before, after, "now", "alice"); !reflect.DeepEqual(events, expected) {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+}
+
+func TestUpdateKeepsHistoryAppendOnly(t *testing.T) {
+	ctx := WithCaller(context.Background(), "alice")
+	factory := NewLocalFactory(t.TempDir())
+	err := Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		networkConfig.Subnets["first"] = "10.116.0.0/24"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		networkConfig.History = nil
+		delete(networkConfig.Subnets, "first")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.History) != 2 || networkConfig.History[0].Action != "allocate" || networkConfig.History[1].Action != "release" {
+		t.Fatalf("Unexpected history %+v", networkConfig.History)
+	}
+}
+
+func TestUpdateTrimsHistoryToItsLimit(t *testing.T) {
+	ctx := context.Background()
+	remote := NewLocalFactory(t.TempDir())("10.116.0.0/14")
+	reserve := func(netmaskId string, historyLimit int) []AuditEvent {
+		err := Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+			networkConfig.HistoryLimit = historyLimit
+			networkConfig.Subnets[netmaskId] = "10.116." + strconv.Itoa(len(networkConfig.Subnets)) + ".0/24"
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		networkConfig, err := remote.ReadRemote(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return networkConfig.History
+	}
+	for i := 0; i < 3; i++ {
+		reserve("first-"+strconv.Itoa(i), 0)
+	}
+	if history := reserve("limited", 2); len(history) != 2 || history[0].NetmaskId != "first-2" || history[1].NetmaskId != "limited" {
+		t.Fatalf("Only the latest 2 events should be kept, got %+v", history)
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	events := []AuditEvent{
+		{Action: "allocate", NetmaskId: "first", NewCidr: "10.116.0.0/24"},
+		{Action: "rename", NetmaskId: "second", OldNetmaskId: "first", OldCidr: "10.116.0.0/24", NewCidr: "10.116.0.0/24"},
+		{Action: "allocate", NetmaskId: "third", NewCidr: "10.116.4.0/22"},
+	}
+	if filtered := FilterHistory(events, "first", nil); !reflect.DeepEqual(filtered, events[:2]) {
+		t.Fatalf("Unexpected events of first %+v", filtered)
+	}
+	_, cidr, _ := net.ParseCIDR("10.116.5.0/24")
+	if filtered := FilterHistory(events, "", cidr); !reflect.DeepEqual(filtered, events[2:]) {
+		t.Fatalf("Unexpected events overlapping %s %+v", cidr, filtered)
+	}
+	if filtered := FilterHistory(events, "", nil); !reflect.DeepEqual(filtered, events) {
+		t.Fatalf("Unexpected unfiltered events %+v", filtered)
+	}
+}
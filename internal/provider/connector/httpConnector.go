@@ -0,0 +1,195 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HttpConnector reserves through a central reservation service, e.g. `cidr-reservator serve`, which decides about
+// the reservations and enforces its policies. Instead of writing whole reservation documents, it requests single
+// reservations from the API of the service below Address:
+//
+//   - GET /v1/pools lists the base cidr ranges as JSON array.
+//   - GET /v1/pools/<base_cidr>/reservations lists the reservations of a base cidr range as JSON array of Reservation.
+//   - POST /v1/pools/<base_cidr>/reservations reserves the range described by a ReservationRequest and returns the
+//     Reservation with status 201.
+//   - DELETE /v1/pools/<base_cidr>/reservations/<netmask_id> releases a reservation; 404 counts as released.
+//
+// Failed requests are reported with the message in the field error of a JSON body, e.g. 422 if the range cannot be
+// reserved. The caller set by WithCaller is sent in the header X-Cidr-Reservator-Caller.
+type HttpConnector struct {
+	Address       string
+	BaseCidrRange string
+	options       HttpOptions
+	client        *http.Client
+}
+
+// HttpOptions configures the endpoint of the http backend.
+type HttpOptions struct {
+	Address string
+	// Token is sent as bearer token, if set.
+	Token string
+}
+
+var _ Reserver = (*HttpConnector)(nil)
+var _ Lister = (*HttpConnector)(nil)
+
+func NewHttp(client *http.Client, options HttpOptions, baseCidr string) HttpConnector {
+	return HttpConnector{strings.TrimSuffix(options.Address, "/"), baseCidr, options, client}
+}
+
+// NewHttpFactory returns a Factory creating HttpConnectors for options.Address, which share one client.
+func NewHttpFactory(options HttpOptions, client *http.Client) (Factory, error) {
+	if !strings.HasPrefix(options.Address, "http://") && !strings.HasPrefix(options.Address, "https://") {
+		return nil, fmt.Errorf("The address %s of the http backend must be an http or https URL!", options.Address)
+	}
+	return func(baseCidr string) Connector {
+		httpConnector := NewHttp(client, options, baseCidr)
+		return &httpConnector
+	}, nil
+}
+
+func (httpConnector *HttpConnector) GetBaseCidrRange() string {
+	return httpConnector.BaseCidrRange
+}
+
+func (httpConnector *HttpConnector) GetLocation() string {
+	return httpConnector.Address
+}
+
+func (httpConnector *HttpConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	var baseCidrs []string
+	if err := httpConnector.do(ctx, http.MethodGet, httpConnector.Address+"/v1/pools", nil, http.StatusOK, &baseCidrs); err != nil {
+		return nil, fmt.Errorf("Failed to list the base cidr ranges of %s: %w", httpConnector.Address, err)
+	}
+	return baseCidrs, nil
+}
+
+// ReadRemote returns the reservations of the base cidr range as reservation document. It lacks everything the
+// service does not list, e.g. the history and exclusions.
+func (httpConnector *HttpConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	networkConfig := NetworkConfig{Subnets: make(map[string]string)}
+	var reservations []Reservation
+	if err := httpConnector.do(ctx, http.MethodGet, httpConnector.url(), nil, http.StatusOK, &reservations); err != nil {
+		return &networkConfig, fmt.Errorf("Failed to read the reservations of %s: %w", httpConnector.BaseCidrRange, err)
+	}
+	for _, reservation := range reservations {
+		networkConfig.Subnets[reservation.NetmaskId] = reservation.Cidr
+		networkConfig.SetMetadata(reservation.NetmaskId, ReservationMetadata{Description: reservation.Description, Owner: reservation.Owner, Labels: reservation.Labels})
+		if reservation.ExpiresAt != "" {
+			if networkConfig.Leases == nil {
+				networkConfig.Leases = make(map[string]Lease)
+			}
+			networkConfig.Leases[reservation.NetmaskId] = Lease{Duration: reservation.LeaseDuration, ExpiresAt: reservation.ExpiresAt}
+		}
+		if reservation.ChildPool {
+			if networkConfig.ChildPools == nil {
+				networkConfig.ChildPools = make(map[string]string)
+			}
+			networkConfig.ChildPools[reservation.NetmaskId] = reservation.Cidr
+		}
+	}
+	return &networkConfig, nil
+}
+
+// WriteRemote always fails, as the service only reserves and releases single reservations.
+func (httpConnector *HttpConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	return fmt.Errorf("The reservation service %s only reserves and releases reservations of %s, other changes are not supported by the http backend!", httpConnector.Address, httpConnector.BaseCidrRange)
+}
+
+func (httpConnector *HttpConnector) Reserve(ctx context.Context, request ReservationRequest) (Reservation, error) {
+	if request.ChildPool {
+		return Reservation{}, fmt.Errorf("Child pools cannot be reserved through the http backend!")
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Reservation{}, err
+	}
+	var reservation Reservation
+	if err := httpConnector.do(ctx, http.MethodPost, httpConnector.url(), body, http.StatusCreated, &reservation); err != nil {
+		tflog.Error(ctx, "The reservation has been rejected", map[string]interface{}{"url": httpConnector.url(), "netmask_id": request.NetmaskId, "error": err.Error()})
+		return Reservation{}, fmt.Errorf("Failed to reserve %s in %s: %w", request.NetmaskId, httpConnector.BaseCidrRange, err)
+	}
+	return reservation, nil
+}
+
+func (httpConnector *HttpConnector) Release(ctx context.Context, netmaskId string) error {
+	err := httpConnector.do(ctx, http.MethodDelete, httpConnector.url()+"/"+url.PathEscape(netmaskId), nil, http.StatusOK, nil)
+	var statusErr statusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to release %s in %s: %w", netmaskId, httpConnector.BaseCidrRange, err)
+	}
+	return nil
+}
+
+// url returns the URL of the reservations of the base cidr range.
+func (httpConnector *HttpConnector) url() string {
+	return httpConnector.Address + "/v1/pools/" + httpConnector.BaseCidrRange + "/reservations"
+}
+
+// do sends a request with body and decodes the response into value, if it has the status expected.
+func (httpConnector *HttpConnector) do(ctx context.Context, method string, url string, body []byte, expected int, value interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if caller := callerOf(ctx); caller != "" {
+		request.Header.Set("X-Cidr-Reservator-Caller", caller)
+	}
+	if httpConnector.options.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+httpConnector.options.Token)
+	}
+	response, err := httpConnector.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != expected {
+		return statusError{response.StatusCode, errorOf(response)}
+	}
+	if value == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// statusError is returned for responses with an unexpected status.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+// errorOf returns the status of response together with the message of its body, preferring the field error of a
+// JSON body.
+func errorOf(response *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	var jsonError struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &jsonError) == nil && jsonError.Error != "" {
+		return fmt.Sprintf("%s: %s", response.Status, jsonError.Error)
+	}
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return fmt.Sprintf("%s: %s", response.Status, message)
+	}
+	return response.Status
+}
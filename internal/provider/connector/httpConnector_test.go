@@ -0,0 +1,89 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The contract of HttpConnector is tested against `cidr-reservator serve` in cmd/cidr-reservator, these tests only
+// cover what does not depend on the service.
+
+func TestHttpConnectorSendsTokenAndCaller(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode([]Reservation{{NetmaskId: "first", Cidr: "10.116.0.0/24", Owner: r.Header.Get("X-Cidr-Reservator-Caller")}})
+	}))
+	t.Cleanup(server.Close)
+	ctx := WithCaller(context.Background(), "alice")
+	factory, err := NewHttpFactory(HttpOptions{Address: server.URL, Token: "wrong"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := factory("10.116.0.0/14").ReadRemote(ctx); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("Expected 401 Unauthorized, got %v", err)
+	}
+	factory, err = NewHttpFactory(HttpOptions{Address: server.URL + "/", Token: "secret"}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkConfig.Subnets["first"] != "10.116.0.0/24" || networkConfig.Metadata["first"].Owner != "alice" {
+		t.Fatalf("Unexpected reservations %+v", networkConfig)
+	}
+	if _, err := NewHttpFactory(HttpOptions{Address: "ipam.example.com"}, server.Client()); err == nil {
+		t.Fatal("Addresses without scheme should be rejected")
+	}
+}
+
+func TestHttpConnectorDoesNotWriteDocuments(t *testing.T) {
+	factory, err := NewHttpFactory(HttpOptions{Address: "https://ipam.example.com"}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := factory("10.116.0.0/14").WriteRemote(&NetworkConfig{}, context.Background()); err == nil {
+		t.Fatal("Writing a reservation document should fail")
+	}
+	if _, err := factory("10.116.0.0/14").(Reserver).Reserve(context.Background(), ReservationRequest{NetmaskId: "pool", PrefixLength: 16, ChildPool: true}); err == nil {
+		t.Fatal("Reserving a child pool should fail")
+	}
+}
+
+func TestHttpConnectorRejectionsAreNotRetried(t *testing.T) {
+	posts := 0
+	status := http.StatusUnprocessableEntity
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	factory, err := NewHttpFactory(HttpOptions{Address: server.URL}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserve := func() error {
+		_, err := factory("10.116.0.0/14").(Reserver).Reserve(context.Background(), ReservationRequest{NetmaskId: "first", PrefixLength: 24})
+		return err
+	}
+	if err := Retry(reserve, Retryable); err == nil || posts != 1 {
+		t.Fatalf("A rejected reservation should be requested once, got %d requests and %v", posts, err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := Retry(reserve, Retryable); err == nil || posts != 5 {
+		t.Fatalf("Server errors should be retried, got %d requests and %v", posts, err)
+	}
+	if !Retryable(fmt.Errorf("%w: index changed", ErrConflict)) || Retryable(errors.New("The netmaskId first already exists!")) {
+		t.Fatal("Only conflicts should be retried among the errors of reservation documents")
+	}
+}
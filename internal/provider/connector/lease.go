@@ -0,0 +1,87 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Lease limits the lifetime of a reservation, e.g. of an ephemeral preview environment. Once it expired, the
+// reservation is reclaimed by the next Update of its base cidr range.
+type Lease struct {
+	// Duration is the lease time as understood by time.ParseDuration, by which renewals extend the lease.
+	Duration string `json:"duration"`
+	// ExpiresAt is the end of the lease in RFC 3339 format.
+	ExpiresAt string `json:"expires_at"`
+}
+
+// NewLease returns a lease for duration starting now.
+func NewLease(duration string) (Lease, error) {
+	parsed, err := time.ParseDuration(duration)
+	if err != nil {
+		return Lease{}, err
+	}
+	if parsed <= 0 {
+		return Lease{}, fmt.Errorf("The lease duration %s must be positive!", duration)
+	}
+	return Lease{duration, now().UTC().Add(parsed).Format(time.RFC3339)}, nil
+}
+
+// SetLease grants netmaskId a lease of duration starting now, or removes its lease if duration is empty.
+func (networkConfig *NetworkConfig) SetLease(netmaskId string, duration string) error {
+	if duration == "" {
+		delete(networkConfig.Leases, netmaskId)
+		return nil
+	}
+	lease, err := NewLease(duration)
+	if err != nil {
+		return err
+	}
+	if networkConfig.Leases == nil {
+		networkConfig.Leases = make(map[string]Lease)
+	}
+	networkConfig.Leases[netmaskId] = lease
+	return nil
+}
+
+// Expired tells whether the lease is over. Leases with an invalid end never expire rather than releasing the
+// reservation too early.
+func (lease Lease) Expired() bool {
+	expiresAt, err := time.Parse(time.RFC3339, lease.ExpiresAt)
+	return err == nil && !now().Before(expiresAt)
+}
+
+// reclaimExpiredLeases releases the reservations with an expired lease, except those promoted into child pools, which
+// have to be released explicitly.
+func (networkConfig *NetworkConfig) reclaimExpiredLeases() map[string]bool {
+	networkConfig.reclaimed = make(map[string]bool)
+	for netmaskId, lease := range networkConfig.Leases {
+		if !lease.Expired() || networkConfig.ChildPools[netmaskId] != "" {
+			continue
+		}
+		delete(networkConfig.Subnets, netmaskId)
+		delete(networkConfig.Metadata, netmaskId)
+		delete(networkConfig.Leases, netmaskId)
+		networkConfig.reclaimed[netmaskId] = true
+	}
+	return networkConfig.reclaimed
+}
+
+// CollectGarbage reclaims the expired leases and drops the expired tombstones of the base cidr range of remote without
+// waiting for the next allocation. It returns the reclaimed netmask ids.
+func CollectGarbage(ctx context.Context, remote Connector) ([]string, error) {
+	var reclaimed []string
+	err := Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+		reclaimed = reclaimed[:0]
+		for netmaskId := range networkConfig.reclaimed {
+			reclaimed = append(reclaimed, netmaskId)
+		}
+		sort.Strings(reclaimed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reclaimed, nil
+}
@@ -0,0 +1,50 @@
+package connector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiredLeasesAreReclaimed(t *testing.T) {
+	defer func() { now = time.Now }()
+	current := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	ctx := context.Background()
+	remote := NewLocalFactory(t.TempDir())("10.116.0.0/14")
+	err := Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+		lease, err := NewLease("1h")
+		if err != nil {
+			return err
+		}
+		networkConfig.Subnets["preview"] = "10.116.0.0/24"
+		networkConfig.Subnets["permanent"] = "10.116.1.0/24"
+		networkConfig.Leases = map[string]Lease{"preview": lease}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed, err := CollectGarbage(ctx, remote); err != nil || len(reclaimed) != 0 {
+		t.Fatalf("Nothing should be reclaimed before the lease expired, got %v, %v", reclaimed, err)
+	}
+	current = current.Add(time.Hour)
+	reclaimed, err := CollectGarbage(ctx, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reclaimed, []string{"preview"}) {
+		t.Fatalf("Expected preview to be reclaimed, got %v", reclaimed)
+	}
+	networkConfig, err := remote.ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(networkConfig.Subnets, map[string]string{"permanent": "10.116.1.0/24"}) || len(networkConfig.Leases) != 0 {
+		t.Fatalf("Unexpected subnets %v and leases %v", networkConfig.Subnets, networkConfig.Leases)
+	}
+	if last := networkConfig.History[len(networkConfig.History)-1]; last.Action != "expire" || last.NetmaskId != "preview" {
+		t.Fatalf("Expected the expiry to be recorded, got %+v", last)
+	}
+}
@@ -0,0 +1,59 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Lister is implemented by connectors, which can enumerate the base cidr ranges with a reservation document at their
+// storage location.
+type Lister interface {
+	ListBaseCidrs(ctx context.Context) ([]string, error)
+}
+
+// ListBaseCidrs returns the base cidr ranges with a reservation document at the storage location of remote.
+func ListBaseCidrs(ctx context.Context, remote Connector) ([]string, error) {
+	lister, ok := remote.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("The storage location %s cannot be listed!", remote.GetLocation())
+	}
+	return lister.ListBaseCidrs(ctx)
+}
+
+// baseCidrsOfDocuments returns the base cidr ranges of the given document names, ignoring other names.
+func baseCidrsOfDocuments(names []string) []string {
+	baseCidrs := make([]string, 0, len(names))
+	for _, name := range names {
+		if baseCidr, ok := baseCidrOfDocument(name); ok {
+			baseCidrs = append(baseCidrs, baseCidr)
+		}
+	}
+	sort.Strings(baseCidrs)
+	return baseCidrs
+}
+
+// baseCidrOfDocument reverses DocumentName, which replaces the separators of the base cidr range with dashes. Any
+// directory or key prefix of name is ignored.
+func baseCidrOfDocument(name string) (string, bool) {
+	encoded := path.Base(name)
+	if !strings.HasPrefix(encoded, "baseCidr-") || !strings.HasSuffix(encoded, ".json") {
+		return "", false
+	}
+	encoded = strings.TrimSuffix(strings.TrimPrefix(encoded, "baseCidr-"), ".json")
+	separator := strings.LastIndex(encoded, "-")
+	if separator == -1 {
+		return "", false
+	}
+	address, prefixLength := encoded[:separator], encoded[separator+1:]
+	for _, candidate := range []string{strings.ReplaceAll(address, "-", "."), strings.ReplaceAll(address, "-", ":")} {
+		baseCidr := candidate + "/" + prefixLength
+		if _, _, err := net.ParseCIDR(baseCidr); err == nil && path.Base(DocumentName(baseCidr)) == path.Base(name) {
+			return baseCidr, true
+		}
+	}
+	return "", false
+}
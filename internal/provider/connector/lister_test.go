@@ -0,0 +1,35 @@
+package connector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBaseCidrOfDocument(t *testing.T) {
+	for _, baseCidr := range []string{"10.116.0.0/14", "fd00:1::/48", "2001:db8:0:1::/64", "::ffff:10.0.0.0/104"} {
+		if decoded, ok := baseCidrOfDocument(DocumentName(baseCidr)); !ok || DocumentName(decoded) != DocumentName(baseCidr) {
+			t.Fatalf("Failed to decode the document name of %s, got %s", baseCidr, decoded)
+		}
+	}
+	if _, ok := baseCidrOfDocument("cidr-reservation/baseCidr-10-116-0-0-14.json.lock"); ok {
+		t.Fatal("Lock files are no reservation documents")
+	}
+}
+
+func TestLocalConnectorListsBaseCidrs(t *testing.T) {
+	ctx := context.Background()
+	factory := NewLocalFactory(t.TempDir())
+	for _, baseCidr := range []string{"10.116.0.0/14", "fd00:1::/48"} {
+		if err := factory(baseCidr).WriteRemote(&NetworkConfig{Subnets: map[string]string{}}, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	baseCidrs, err := ListBaseCidrs(ctx, factory("0.0.0.0/0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(baseCidrs, []string{"10.116.0.0/14", "fd00:1::/48"}) {
+		t.Fatalf("Unexpected base cidrs %v", baseCidrs)
+	}
+}
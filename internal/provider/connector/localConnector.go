@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LocalConnector keeps the reservation documents in a local directory. Concurrent writers are synchronized with a
+// file lock next to the document, which also holds a generation counter working like the GCS generation precondition.
+type LocalConnector struct {
+	Directory     string
+	BaseCidrRange string
+	FileName      string
+	generation    int64
+}
+
+var _ Connector = (*LocalConnector)(nil)
+var _ Lister = (*LocalConnector)(nil)
+
+func NewLocal(directory string, baseCidr string) LocalConnector {
+	return LocalConnector{directory, baseCidr, DocumentName(baseCidr), -1}
+}
+
+// NewLocalFactory returns a Factory creating LocalConnectors for the given directory.
+func NewLocalFactory(directory string) Factory {
+	return func(baseCidr string) Connector {
+		localConnector := NewLocal(directory, baseCidr)
+		return &localConnector
+	}
+}
+
+func (local *LocalConnector) GetBaseCidrRange() string {
+	return local.BaseCidrRange
+}
+
+func (local *LocalConnector) GetLocation() string {
+	return local.Directory
+}
+
+func (local *LocalConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(local.Directory, filepath.FromSlash(documentPrefix)+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	return baseCidrsOfDocuments(names), nil
+}
+
+func (local *LocalConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	networkConfig := NetworkConfig{}
+	lockFile, err := local.lock(false)
+	if err != nil {
+		return &networkConfig, err
+	}
+	defer local.unlock(lockFile)
+	generation, err := readGeneration(lockFile)
+	if err != nil {
+		return &networkConfig, err
+	}
+	slurp, err := os.ReadFile(local.path())
+	if errors.Is(err, fs.ErrNotExist) {
+		local.generation = -1
+		return &networkConfig, ErrNotExist
+	}
+	if err != nil {
+		return &networkConfig, err
+	}
+	local.generation = generation
+	if err := json.Unmarshal(slurp, &networkConfig); err != nil {
+		return &networkConfig, err
+	}
+	return &networkConfig, nil
+}
+
+func (local *LocalConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	lockFile, err := local.lock(true)
+	if err != nil {
+		return err
+	}
+	defer local.unlock(lockFile)
+	generation, err := readGeneration(lockFile)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(local.path())
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if (local.generation == -1 && exists) || (local.generation != -1 && local.generation != generation) {
+		return fmt.Errorf("%w: expected generation %d of %s, but found %d", ErrConflict, local.generation, local.path(), generation)
+	}
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so readers ignoring the lock never see a partially written document
+	tmpFile := local.path() + ".tmp"
+	if err := os.WriteFile(tmpFile, marshalled, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, local.path()); err != nil {
+		return err
+	}
+	if err := writeGeneration(lockFile, generation+1); err != nil {
+		return err
+	}
+	local.generation = generation + 1
+	return nil
+}
+
+func (local *LocalConnector) path() string {
+	return filepath.Join(local.Directory, filepath.FromSlash(local.FileName))
+}
+
+func (local *LocalConnector) lock(exclusive bool) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(local.path()), 0755); err != nil {
+		return nil, err
+	}
+	lockFile, err := os.OpenFile(local.path()+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := acquireFileLock(lockFile, exclusive); err != nil {
+		lockFile.Close()
+		return nil, err
+	}
+	return lockFile, nil
+}
+
+func (local *LocalConnector) unlock(lockFile *os.File) {
+	_ = releaseFileLock(lockFile)
+	_ = lockFile.Close()
+}
+
+func readGeneration(lockFile *os.File) (int64, error) {
+	if _, err := lockFile.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	content, err := io.ReadAll(lockFile)
+	if err != nil {
+		return 0, err
+	}
+	if len(strings.TrimSpace(string(content))) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+}
+
+func writeGeneration(lockFile *os.File, generation int64) error {
+	if err := lockFile.Truncate(0); err != nil {
+		return err
+	}
+	_, err := lockFile.WriteAt([]byte(strconv.FormatInt(generation, 10)), 0)
+	return err
+}
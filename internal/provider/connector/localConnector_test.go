@@ -0,0 +1,98 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestLocalConnectorConformance(t *testing.T) {
+	testConnectorConformance(t, NewLocalFactory(t.TempDir()))
+}
+
+func TestLocalConnectorUsesGcsLayout(t *testing.T) {
+	directory := t.TempDir()
+	localConnector := NewLocal(directory, "10.116.0.0/14")
+	if err := localConnector.WriteRemote(&NetworkConfig{Subnets: map[string]string{}}, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(directory, "cidr-reservation", "baseCidr-10-116-0-0-14.json")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalConnectorConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	factory := NewLocalFactory(t.TempDir())
+	writers := 16
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			localConnector := factory("10.116.0.0/14")
+			for {
+				networkConfig, err := localConnector.ReadRemote(ctx)
+				if errors.Is(err, ErrNotExist) {
+					networkConfig = &NetworkConfig{Subnets: make(map[string]string)}
+				} else if err != nil {
+					errs <- err
+					return
+				}
+				networkConfig.Subnets[fmt.Sprintf("writer%d", i)] = fmt.Sprintf("10.116.%d.0/24", i)
+				err = localConnector.WriteRemote(networkConfig, ctx)
+				if err == nil {
+					return
+				}
+				if !errors.Is(err, ErrConflict) {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Subnets) != writers {
+		t.Fatalf("Expected %d subnets, got %v", writers, networkConfig.Subnets)
+	}
+}
+
+func TestUpdateCreatesMissingDocument(t *testing.T) {
+	ctx := context.Background()
+	factory := NewLocalFactory(t.TempDir())
+	err := Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		networkConfig.Subnets["first"] = "10.116.0.0/24"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	modifyErr := errors.New("modify failed")
+	err = Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		delete(networkConfig.Subnets, "first")
+		return modifyErr
+	})
+	if err != modifyErr {
+		t.Fatalf("The error of modify should be returned as is, got %v", err)
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkConfig.Subnets["first"] != "10.116.0.0/24" {
+		t.Fatalf("A failed modify must not be written, got %v", networkConfig.Subnets)
+	}
+}
@@ -0,0 +1,291 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// PostgresConnector keeps the reservations of a base cidr range as rows, one per netmask_id, in a PostgreSQL database.
+// Modifications run within SERIALIZABLE transactions, so conflicting allocations are detected by the database instead
+// of by rewriting whole documents.
+type PostgresConnector struct {
+	Database      string
+	BaseCidrRange string
+	snapshot      *NetworkConfig
+	db            *sql.DB
+}
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS cidr_reservator_pools (
+	base_cidr TEXT PRIMARY KEY,
+	document  JSONB NOT NULL DEFAULT '{}'
+);
+CREATE TABLE IF NOT EXISTS cidr_reservator_reservations (
+	base_cidr  TEXT NOT NULL REFERENCES cidr_reservator_pools (base_cidr),
+	netmask_id TEXT NOT NULL,
+	netmask    CIDR NOT NULL,
+	PRIMARY KEY (base_cidr, netmask_id)
+);`
+
+var _ TransactionalConnector = (*PostgresConnector)(nil)
+var _ Lister = (*PostgresConnector)(nil)
+
+func NewPostgres(db *sql.DB, database string, baseCidr string) PostgresConnector {
+	return PostgresConnector{database, baseCidr, nil, db}
+}
+
+// NewPostgresFactory connects to the database, creates the reservation tables if necessary and returns a Factory
+// creating PostgresConnectors, which share the connection pool. An empty connectionString falls back to the PG*
+// environment variables.
+func NewPostgresFactory(ctx context.Context, connectionString string) (Factory, error) {
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, postgresSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	var database string
+	if err := db.QueryRowContext(ctx, "SELECT current_database()").Scan(&database); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return func(baseCidr string) Connector {
+		postgresConnector := NewPostgres(db, database, baseCidr)
+		return &postgresConnector
+	}, nil
+}
+
+func (pg *PostgresConnector) GetBaseCidrRange() string {
+	return pg.BaseCidrRange
+}
+
+func (pg *PostgresConnector) GetLocation() string {
+	return pg.Database
+}
+
+func (pg *PostgresConnector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	rows, err := pg.db.QueryContext(ctx, "SELECT base_cidr FROM cidr_reservator_pools ORDER BY base_cidr")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var baseCidrs []string
+	for rows.Next() {
+		var baseCidr string
+		if err := rows.Scan(&baseCidr); err != nil {
+			return nil, err
+		}
+		baseCidrs = append(baseCidrs, baseCidr)
+	}
+	return baseCidrs, rows.Err()
+}
+
+func (pg *PostgresConnector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	var networkConfig *NetworkConfig
+	err := pg.inTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		networkConfig, err = pg.load(ctx, tx)
+		return err
+	})
+	if errors.Is(err, ErrNotExist) {
+		pg.snapshot = nil
+		return &NetworkConfig{}, ErrNotExist
+	}
+	if err != nil {
+		return &NetworkConfig{}, err
+	}
+	pg.snapshot, err = copyNetworkConfig(networkConfig)
+	return networkConfig, err
+}
+
+// WriteRemote stores the changes compared to the current rows, if those still match the ones of the last ReadRemote.
+func (pg *PostgresConnector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	err := pg.inTransaction(ctx, func(tx *sql.Tx) error {
+		current, err := pg.load(ctx, tx)
+		if err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+		equal, err := sameNetworkConfig(current, pg.snapshot)
+		if err != nil {
+			return err
+		}
+		if !equal {
+			return fmt.Errorf("%w: the reservations of %s changed since they have been read", ErrConflict, pg.BaseCidrRange)
+		}
+		return pg.store(ctx, tx, current, networkConfig)
+	})
+	if err != nil {
+		return err
+	}
+	pg.snapshot, err = copyNetworkConfig(networkConfig)
+	return err
+}
+
+func (pg *PostgresConnector) Transaction(ctx context.Context, modify func(networkConfig *NetworkConfig) error) error {
+	return pg.inTransaction(ctx, func(tx *sql.Tx) error {
+		current, err := pg.load(ctx, tx)
+		if err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+		networkConfig := &NetworkConfig{Subnets: make(map[string]string)}
+		if current != nil {
+			if networkConfig, err = copyNetworkConfig(current); err != nil {
+				return err
+			}
+		}
+		if err := modify(networkConfig); err != nil {
+			return err
+		}
+		return pg.store(ctx, tx, current, networkConfig)
+	})
+}
+
+func (pg *PostgresConnector) inTransaction(ctx context.Context, run func(tx *sql.Tx) error) error {
+	tx, err := pg.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := run(tx); err != nil {
+		return postgresConflict(err)
+	}
+	return postgresConflict(tx.Commit())
+}
+
+// postgresConflict maps the errors of concurrent transactions to ErrConflict, so they are retried like failed
+// conditional writes of the other backends.
+func postgresConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01", "23505":
+			return fmt.Errorf("%w: %s", ErrConflict, err)
+		}
+	}
+	return err
+}
+
+// load returns the reservation document assembled from the pool row and its reservation rows.
+func (pg *PostgresConnector) load(ctx context.Context, tx *sql.Tx) (*NetworkConfig, error) {
+	var document []byte
+	err := tx.QueryRowContext(ctx, "SELECT document FROM cidr_reservator_pools WHERE base_cidr = $1", pg.BaseCidrRange).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	networkConfig := NetworkConfig{}
+	if err := json.Unmarshal(document, &networkConfig); err != nil {
+		return nil, err
+	}
+	networkConfig.Subnets = make(map[string]string)
+	rows, err := tx.QueryContext(ctx, "SELECT netmask_id, netmask::text FROM cidr_reservator_reservations WHERE base_cidr = $1", pg.BaseCidrRange)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var netmaskId, netmask string
+		if err := rows.Scan(&netmaskId, &netmask); err != nil {
+			return nil, err
+		}
+		networkConfig.Subnets[netmaskId] = netmask
+	}
+	return &networkConfig, rows.Err()
+}
+
+// store writes the differences between current (nil if the pool does not exist yet) and networkConfig.
+func (pg *PostgresConnector) store(ctx context.Context, tx *sql.Tx, current *NetworkConfig, networkConfig *NetworkConfig) error {
+	document, err := poolDocument(networkConfig)
+	if err != nil {
+		return err
+	}
+	currentSubnets := map[string]string{}
+	if current == nil {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO cidr_reservator_pools (base_cidr, document) VALUES ($1, $2)", pg.BaseCidrRange, document); err != nil {
+			return err
+		}
+	} else {
+		currentDocument, err := poolDocument(current)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(document, currentDocument) {
+			if _, err := tx.ExecContext(ctx, "UPDATE cidr_reservator_pools SET document = $2 WHERE base_cidr = $1", pg.BaseCidrRange, document); err != nil {
+				return err
+			}
+		}
+		currentSubnets = current.Subnets
+	}
+	for netmaskId := range currentSubnets {
+		if _, contains := networkConfig.Subnets[netmaskId]; !contains {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM cidr_reservator_reservations WHERE base_cidr = $1 AND netmask_id = $2", pg.BaseCidrRange, netmaskId); err != nil {
+				return err
+			}
+		}
+	}
+	for netmaskId, netmask := range networkConfig.Subnets {
+		currentNetmask, contains := currentSubnets[netmaskId]
+		if !contains {
+			_, err = tx.ExecContext(ctx, "INSERT INTO cidr_reservator_reservations (base_cidr, netmask_id, netmask) VALUES ($1, $2, $3)", pg.BaseCidrRange, netmaskId, netmask)
+		} else if currentNetmask != netmask {
+			_, err = tx.ExecContext(ctx, "UPDATE cidr_reservator_reservations SET netmask = $3 WHERE base_cidr = $1 AND netmask_id = $2", pg.BaseCidrRange, netmaskId, netmask)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// poolDocument returns everything of the reservation document except the subnets, which are kept as rows.
+func poolDocument(networkConfig *NetworkConfig) ([]byte, error) {
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return nil, err
+	}
+	document := map[string]json.RawMessage{}
+	if err := json.Unmarshal(marshalled, &document); err != nil {
+		return nil, err
+	}
+	delete(document, "subnets")
+	return json.Marshal(document)
+}
+
+func copyNetworkConfig(networkConfig *NetworkConfig) (*NetworkConfig, error) {
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return nil, err
+	}
+	copied := NetworkConfig{}
+	if err := json.Unmarshal(marshalled, &copied); err != nil {
+		return nil, err
+	}
+	if copied.Subnets == nil {
+		copied.Subnets = make(map[string]string)
+	}
+	return &copied, nil
+}
+
+func sameNetworkConfig(a *NetworkConfig, b *NetworkConfig) (bool, error) {
+	if a == nil || b == nil {
+		return a == b, nil
+	}
+	marshalledA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	marshalledB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(marshalledA, marshalledB), nil
+}
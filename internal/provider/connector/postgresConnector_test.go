@@ -0,0 +1,79 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Runs against a PostgreSQL database given as key/value connection string, e.g.
+//
+//	docker run -p 5432:5432 -e POSTGRES_PASSWORD=postgres postgres
+//	CIDR_RESERVATOR_POSTGRES_CONNECTION_STRING="host=localhost user=postgres password=postgres sslmode=disable" go test ./...
+func newPostgresTestFactory(t *testing.T) Factory {
+	connectionString := os.Getenv("CIDR_RESERVATOR_POSTGRES_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("CIDR_RESERVATOR_POSTGRES_CONNECTION_STRING is not set")
+	}
+	db, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	schemaName := fmt.Sprintf("cidr_reservator_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schemaName); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := NewPostgresFactory(context.Background(), connectionString+" search_path="+schemaName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return factory
+}
+
+func TestPostgresConnectorConformance(t *testing.T) {
+	testConnectorConformance(t, newPostgresTestFactory(t))
+}
+
+func TestPostgresConnectorConcurrentTransactions(t *testing.T) {
+	factory := newPostgresTestFactory(t)
+	ctx := context.Background()
+	writers := 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				err := Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+					networkConfig.Subnets[fmt.Sprintf("writer%d", i)] = fmt.Sprintf("10.116.%d.0/24", len(networkConfig.Subnets))
+					return nil
+				})
+				if !errors.Is(err, ErrConflict) {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Subnets) != writers {
+		t.Fatalf("Expected %d subnets, got %v", writers, networkConfig.Subnets)
+	}
+}
@@ -0,0 +1,92 @@
+package connector
+
+import (
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"time"
+)
+
+// Tombstone keeps a released subnet occupied until its quarantine period is over, so stale firewall rules and routes
+// do not point at the next owner right away.
+type Tombstone struct {
+	NetmaskId string `json:"netmask_id"`
+	Cidr      string `json:"cidr"`
+	// Until is the end of the quarantine period in RFC 3339 format.
+	Until string `json:"until"`
+}
+
+// ActiveTombstones returns the tombstones, whose quarantine period is not over yet. Tombstones with an invalid end
+// are kept forever rather than handing out their range too early.
+func (networkConfig *NetworkConfig) ActiveTombstones() []Tombstone {
+	var active []Tombstone
+	for _, tombstone := range networkConfig.Tombstones {
+		until, err := time.Parse(time.RFC3339, tombstone.Until)
+		if err != nil || now().Before(until) {
+			active = append(active, tombstone)
+		}
+	}
+	return active
+}
+
+// quarantine drops the expired tombstones and adds one for each subnet released or reallocated by events, if the pool
+// has a quarantine period. A reallocated subnet is only quarantined as far as its new subnet does not cover it, so
+// resizes in place quarantine nothing when growing and the freed part when shrinking. Growing in place also ends the
+// quarantine of the ranges the reservation got back.
+func (networkConfig *NetworkConfig) quarantine(events []AuditEvent, timestamp time.Time) error {
+	networkConfig.Tombstones = networkConfig.ActiveTombstones()
+	if networkConfig.QuarantinePeriod == "" {
+		return nil
+	}
+	period, err := time.ParseDuration(networkConfig.QuarantinePeriod)
+	if err != nil {
+		return fmt.Errorf("The quarantine period %s of the reservation document is invalid: %s", networkConfig.QuarantinePeriod, err)
+	}
+	until := timestamp.Add(period).Format(time.RFC3339)
+	for _, event := range events {
+		switch event.Action {
+		case "release", "expire":
+			networkConfig.Tombstones = append(networkConfig.Tombstones, Tombstone{event.NetmaskId, event.OldCidr, until})
+		case "reallocate":
+			freed, err := cidrCalculator.Uncovered(event.OldCidr, event.NewCidr)
+			if err != nil {
+				return err
+			}
+			if err := networkConfig.dropTombstonesCovered(event.NetmaskId, event.NewCidr); err != nil {
+				return err
+			}
+			for _, cidr := range freed {
+				networkConfig.Tombstones = append(networkConfig.Tombstones, Tombstone{event.NetmaskId, cidr, until})
+			}
+		}
+	}
+	return nil
+}
+
+// dropTombstonesCovered drops the tombstones of netmaskId, which its subnet cidr covers again.
+func (networkConfig *NetworkConfig) dropTombstonesCovered(netmaskId string, cidr string) error {
+	kept := networkConfig.Tombstones[:0]
+	for _, tombstone := range networkConfig.Tombstones {
+		uncovered, err := cidrCalculator.Uncovered(tombstone.Cidr, cidr)
+		if err != nil {
+			return err
+		}
+		if tombstone.NetmaskId != netmaskId || len(uncovered) != 0 {
+			kept = append(kept, tombstone)
+		}
+	}
+	networkConfig.Tombstones = kept
+	return nil
+}
+
+// ResizeInPlace works like cidrCalculator.ResizeInPlace on the occupied subnets, but ignores the tombstones of
+// netmaskId itself, so a reservation may grow back into the ranges it freed by shrinking.
+func (networkConfig *NetworkConfig) ResizeInPlace(netmaskId string, prefixLength int, baseCidrRange string) (string, error) {
+	var tombstones []Tombstone
+	for _, tombstone := range networkConfig.ActiveTombstones() {
+		if tombstone.NetmaskId != netmaskId {
+			tombstones = append(tombstones, tombstone)
+		}
+	}
+	occupied := networkConfig.occupiedSubnets(tombstones)
+	return cidrCalculator.ResizeInPlace(&occupied, netmaskId, prefixLength, baseCidrRange)
+}
@@ -0,0 +1,83 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTombstonesExpireAfterQuarantinePeriod(t *testing.T) {
+	defer func() { now = time.Now }()
+	current := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	ctx := context.Background()
+	remote := NewLocalFactory(t.TempDir())("10.116.0.0/14")
+	err := Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+		networkConfig.QuarantinePeriod = "24h"
+		networkConfig.Subnets["first"] = "10.116.0.0/24"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+		delete(networkConfig.Subnets, "first")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig, err := remote.ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if occupied := networkConfig.OccupiedSubnets(); occupied[TombstonePrefix+"10.116.0.0/24"] != "10.116.0.0/24" {
+		t.Fatalf("The released range should be occupied during its quarantine, got %v", occupied)
+	}
+	current = current.Add(25 * time.Hour)
+	if occupied := networkConfig.OccupiedSubnets(); len(occupied) != 0 {
+		t.Fatalf("The released range should be free after its quarantine, got %v", occupied)
+	}
+	err = Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkConfig, err = remote.ReadRemote(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Tombstones) != 0 {
+		t.Fatalf("Expired tombstones should be dropped, got %v", networkConfig.Tombstones)
+	}
+}
+
+func TestReallocationsQuarantineOnlyTheFreedRanges(t *testing.T) {
+	ctx := context.Background()
+	remote := NewLocalFactory(t.TempDir())("10.116.0.0/14")
+	resize := func(subnet string) []Tombstone {
+		err := Update(ctx, remote, func(networkConfig *NetworkConfig) error {
+			networkConfig.QuarantinePeriod = "24h"
+			networkConfig.Subnets["first"] = subnet
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		networkConfig, err := remote.ReadRemote(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return networkConfig.Tombstones
+	}
+	resize("10.116.0.0/24")
+	if tombstones := resize("10.116.0.0/26"); len(tombstones) != 2 || tombstones[0].Cidr != "10.116.0.64/26" || tombstones[1].Cidr != "10.116.0.128/25" {
+		t.Fatalf("Shrinking should only quarantine the freed ranges, got %v", tombstones)
+	}
+	if tombstones := resize("10.116.0.0/25"); len(tombstones) != 1 || tombstones[0].Cidr != "10.116.0.128/25" {
+		t.Fatalf("Growing should end the quarantine of the ranges got back, got %v", tombstones)
+	}
+	if tombstones := resize("10.116.1.0/25"); len(tombstones) != 2 || tombstones[1].Cidr != "10.116.0.0/25" {
+		t.Fatalf("Relocating should quarantine the whole old range, got %v", tombstones)
+	}
+}
@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+)
+
+// ErrNetmaskIdExists is returned by Reserve, if the netmask id is already reserved.
+var ErrNetmaskIdExists = errors.New("netmask id already exists")
+
+// Reserver is implemented by connectors of reservation services, which decide about the reservations themselves
+// instead of storing the documents changed by the provider. Update and WriteRemote are not supported by them.
+type Reserver interface {
+	Connector
+	// Reserve makes the reservation described by request and returns it.
+	Reserve(ctx context.Context, request ReservationRequest) (Reservation, error)
+	// Release releases the reservation netmaskId; releasing a reservation, which does not exist, succeeds.
+	Release(ctx context.Context, netmaskId string) error
+}
+
+// Reservation is a single reservation, as listed by the command line and exchanged with reservation services.
+type Reservation struct {
+	NetmaskId     string            `json:"netmask_id"`
+	Cidr          string            `json:"cidr"`
+	Owner         string            `json:"owner,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	LeaseDuration string            `json:"lease_duration,omitempty"`
+	ExpiresAt     string            `json:"expires_at,omitempty"`
+	ChildPool     bool              `json:"child_pool,omitempty"`
+}
+
+// Reservation returns the reservation netmaskId together with its metadata and lease.
+func (networkConfig *NetworkConfig) Reservation(netmaskId string) Reservation {
+	metadata := networkConfig.Metadata[netmaskId]
+	lease := networkConfig.Leases[netmaskId]
+	return Reservation{
+		NetmaskId:     netmaskId,
+		Cidr:          networkConfig.Subnets[netmaskId],
+		Owner:         metadata.Owner,
+		Description:   metadata.Description,
+		Labels:        metadata.Labels,
+		LeaseDuration: lease.Duration,
+		ExpiresAt:     lease.ExpiresAt,
+		ChildPool:     networkConfig.ChildPools[netmaskId] != "",
+	}
+}
+
+// ReservationRequest describes a new reservation, either of the next free range of PrefixLength or of RequestedCidr.
+type ReservationRequest struct {
+	NetmaskId     string
+	PrefixLength  int
+	RequestedCidr string
+	Strategy      cidrCalculator.Strategy
+	Metadata      ReservationMetadata
+	// LeaseDuration limits the lifetime of the reservation, see NewLease.
+	LeaseDuration string
+	// ChildPool lists the reservation in ChildPools, which does not promote the reserved range itself yet.
+	ChildPool bool
+}
+
+// Reserve adds the reservation described by request to the reservation document of baseCidr and returns the reserved
+// range. Child pools are verified with connectors of newConnector.
+func (networkConfig *NetworkConfig) Reserve(ctx context.Context, newConnector Factory, baseCidr string, request ReservationRequest) (string, error) {
+	if _, contains := networkConfig.Subnets[request.NetmaskId]; contains {
+		return "", fmt.Errorf("%w: %s", ErrNetmaskIdExists, request.NetmaskId)
+	}
+	if err := networkConfig.VerifyChildPool(ctx, newConnector, baseCidr); err != nil {
+		return "", err
+	}
+	occupied := networkConfig.OccupiedSubnets()
+	var netmask string
+	var err error
+	if request.RequestedCidr != "" {
+		netmask, err = cidrCalculator.VerifyRequestedNetmask(&occupied, request.RequestedCidr, baseCidr)
+	} else {
+		netmask, err = cidrCalculator.NextNetmask(&occupied, request.PrefixLength, baseCidr, request.Strategy)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := networkConfig.SetLease(request.NetmaskId, request.LeaseDuration); err != nil {
+		return "", err
+	}
+	networkConfig.Subnets[request.NetmaskId] = netmask
+	networkConfig.SetMetadata(request.NetmaskId, request.Metadata)
+	if request.ChildPool {
+		if networkConfig.ChildPools == nil {
+			networkConfig.ChildPools = make(map[string]string)
+		}
+		networkConfig.ChildPools[request.NetmaskId] = netmask
+	}
+	return netmask, nil
+}
+
+// Release removes the reservation netmaskId together with its metadata, lease and child pool entry.
+func (networkConfig *NetworkConfig) Release(netmaskId string) {
+	delete(networkConfig.Subnets, netmaskId)
+	delete(networkConfig.Metadata, netmaskId)
+	delete(networkConfig.Leases, netmaskId)
+	delete(networkConfig.ChildPools, netmaskId)
+}
+
+// reservationRequestBody is the JSON form of a ReservationRequest sent to reservation services. Child pools cannot be
+// requested from them.
+type reservationRequestBody struct {
+	NetmaskId     string            `json:"netmask_id"`
+	PrefixLength  int               `json:"prefix_length,omitempty"`
+	Cidr          string            `json:"cidr,omitempty"`
+	Strategy      string            `json:"allocation_strategy,omitempty"`
+	Owner         string            `json:"owner,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	LeaseDuration string            `json:"lease_duration,omitempty"`
+}
+
+// MarshalJSON sends either the requested cidr or the prefix length, as services reject requests with both.
+func (request ReservationRequest) MarshalJSON() ([]byte, error) {
+	prefixLength := request.PrefixLength
+	if request.RequestedCidr != "" {
+		prefixLength = 0
+	}
+	return json.Marshal(reservationRequestBody{
+		NetmaskId:     request.NetmaskId,
+		PrefixLength:  prefixLength,
+		Cidr:          request.RequestedCidr,
+		Strategy:      string(request.Strategy),
+		Owner:         request.Metadata.Owner,
+		Description:   request.Metadata.Description,
+		Labels:        request.Metadata.Labels,
+		LeaseDuration: request.LeaseDuration,
+	})
+}
+
+// UnmarshalJSON rejects unknown fields, so misspelled ones do not silently request another range.
+func (request *ReservationRequest) UnmarshalJSON(data []byte) error {
+	var body reservationRequestBody
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		return err
+	}
+	*request = ReservationRequest{
+		NetmaskId:     body.NetmaskId,
+		PrefixLength:  body.PrefixLength,
+		RequestedCidr: body.Cidr,
+		Strategy:      cidrCalculator.Strategy(body.Strategy),
+		Metadata:      ReservationMetadata{Description: body.Description, Owner: body.Owner, Labels: body.Labels},
+		LeaseDuration: body.LeaseDuration,
+	}
+	return nil
+}
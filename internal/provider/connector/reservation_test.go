@@ -0,0 +1,30 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReserveAndRelease(t *testing.T) {
+	ctx := context.Background()
+	factory := NewLocalFactory(t.TempDir())
+	networkConfig := &NetworkConfig{Subnets: map[string]string{"first": "10.116.0.0/24"}}
+	netmask, err := networkConfig.Reserve(ctx, factory, "10.116.0.0/14", ReservationRequest{NetmaskId: "second", PrefixLength: 24, LeaseDuration: "1h", ChildPool: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask != "10.116.1.0/24" || networkConfig.Subnets["second"] != netmask || networkConfig.ChildPools["second"] != netmask || networkConfig.Leases["second"].ExpiresAt == "" {
+		t.Fatalf("Unexpected reservation %s in %+v", netmask, networkConfig)
+	}
+	if _, err := networkConfig.Reserve(ctx, factory, "10.116.0.0/14", ReservationRequest{NetmaskId: "first", PrefixLength: 24}); !errors.Is(err, ErrNetmaskIdExists) {
+		t.Fatalf("Expected ErrNetmaskIdExists, got %v", err)
+	}
+	if _, err := networkConfig.Reserve(ctx, factory, "10.116.0.0/14", ReservationRequest{NetmaskId: "third", RequestedCidr: "10.116.0.0/23"}); err == nil {
+		t.Fatal("Overlapping requested cidr ranges should be rejected")
+	}
+	networkConfig.Release("second")
+	if _, contains := networkConfig.Subnets["second"]; contains || networkConfig.ChildPools["second"] != "" || networkConfig.Leases["second"].ExpiresAt != "" {
+		t.Fatalf("second should have been released completely: %+v", networkConfig)
+	}
+}
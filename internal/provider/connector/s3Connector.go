@@ -0,0 +1,153 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"io"
+	"net/http"
+)
+
+// S3Connector keeps the reservation documents in an S3 bucket. The ETag of the document serves as version token,
+// writes are conditional on it with If-Match, or with If-None-Match when the document does not exist yet.
+type S3Connector struct {
+	BucketName    string
+	BaseCidrRange string
+	FileName      string
+	etag          string
+	client        *s3.Client
+}
+
+// S3Options configures the S3 client; credentials are taken from the default AWS credential chain.
+type S3Options struct {
+	Region string
+	// Endpoint overrides the S3 endpoint, e.g. to use an S3 compatible storage like MinIO.
+	Endpoint     string
+	UsePathStyle bool
+}
+
+var _ Connector = (*S3Connector)(nil)
+var _ Lister = (*S3Connector)(nil)
+
+func NewS3(client *s3.Client, bucketName string, baseCidr string) S3Connector {
+	return S3Connector{bucketName, baseCidr, DocumentName(baseCidr), "", client}
+}
+
+// NewS3Factory returns a Factory creating S3Connectors for the given bucket, which share one S3 client.
+func NewS3Factory(ctx context.Context, bucketName string, options S3Options) (Factory, error) {
+	client, err := NewS3Client(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	return func(baseCidr string) Connector {
+		s3Connector := NewS3(client, bucketName, baseCidr)
+		return &s3Connector
+	}, nil
+}
+
+func NewS3Client(ctx context.Context, options S3Options) (*s3.Client, error) {
+	var loadOptions []func(*config.LoadOptions) error
+	if options.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(options.Region))
+	}
+	awsConfig, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		if options.Endpoint != "" {
+			o.BaseEndpoint = aws.String(options.Endpoint)
+		}
+		o.UsePathStyle = options.UsePathStyle
+	}), nil
+}
+
+func (s3c *S3Connector) GetBaseCidrRange() string {
+	return s3c.BaseCidrRange
+}
+
+func (s3c *S3Connector) GetLocation() string {
+	return s3c.BucketName
+}
+
+func (s3c *S3Connector) ListBaseCidrs(ctx context.Context) ([]string, error) {
+	var names []string
+	paginator := s3.NewListObjectsV2Paginator(s3c.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s3c.BucketName),
+		Prefix: aws.String(documentPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, aws.ToString(object.Key))
+		}
+	}
+	return baseCidrsOfDocuments(names), nil
+}
+
+func (s3c *S3Connector) ReadRemote(ctx context.Context) (*NetworkConfig, error) {
+	networkConfig := NetworkConfig{}
+	output, err := s3c.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s3c.BucketName),
+		Key:    aws.String(s3c.FileName),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		s3c.etag = ""
+		return &networkConfig, ErrNotExist
+	}
+	if err != nil {
+		return &networkConfig, err
+	}
+	defer output.Body.Close()
+	slurp, err := io.ReadAll(output.Body)
+	if err != nil {
+		return &networkConfig, err
+	}
+	s3c.etag = aws.ToString(output.ETag)
+	if err := json.Unmarshal(slurp, &networkConfig); err != nil {
+		return &networkConfig, err
+	}
+	return &networkConfig, nil
+}
+
+func (s3c *S3Connector) WriteRemote(networkConfig *NetworkConfig, ctx context.Context) error {
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s3c.BucketName),
+		Key:         aws.String(s3c.FileName),
+		Body:        bytes.NewReader(marshalled),
+		ContentType: aws.String("application/json"),
+	}
+	if s3c.etag == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(s3c.etag)
+	}
+	output, err := s3c.client.PutObject(ctx, input)
+	if err != nil {
+		tflog.Error(ctx, "Failed to write file to S3", map[string]interface{}{"error": err, "etag": s3c.etag})
+		var responseErr *awshttp.ResponseError
+		// 409 is returned by S3, if a concurrent conditional write to the same key is still in progress
+		if errors.As(err, &responseErr) && (responseErr.HTTPStatusCode() == http.StatusPreconditionFailed || responseErr.HTTPStatusCode() == http.StatusConflict) {
+			return fmt.Errorf("%w: %s", ErrConflict, err)
+		}
+		return err
+	}
+	s3c.etag = aws.ToString(output.ETag)
+	return nil
+}
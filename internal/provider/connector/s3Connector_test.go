@@ -0,0 +1,37 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"os"
+	"testing"
+	"time"
+)
+
+// Runs against an S3 compatible storage, e.g. a local MinIO:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	AWS_ACCESS_KEY_ID=minioadmin AWS_SECRET_ACCESS_KEY=minioadmin CIDR_RESERVATOR_S3_ENDPOINT=http://localhost:9000 go test ./...
+func TestS3ConnectorConformance(t *testing.T) {
+	endpoint := os.Getenv("CIDR_RESERVATOR_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("CIDR_RESERVATOR_S3_ENDPOINT is not set")
+	}
+	ctx := context.Background()
+	options := S3Options{Region: "us-east-1", Endpoint: endpoint, UsePathStyle: true}
+	client, err := NewS3Client(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucketName := fmt.Sprintf("cidr-reservator-test-%d", time.Now().UnixNano())
+	if _, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucketName)}); err != nil {
+		t.Fatal(err)
+	}
+	factory, err := NewS3Factory(ctx, bucketName, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testConnectorConformance(t, factory)
+}
@@ -0,0 +1,74 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CurrentSchemaVersion is the version of the reservation document format written by this provider.
+//
+//	1: {"subnets": {...}}, the format before schema_version has been introduced
+//	2: adds exclusions, metadata, child_pools and parent
+//	3: adds history
+//	4: adds quarantine_period and tombstones
+//	5: adds leases
+//	6: adds history_limit
+const CurrentSchemaVersion = 6
+
+// ErrNewerSchemaVersion is returned by Update, if the reservation document has been written by a newer version of the
+// provider.
+var ErrNewerSchemaVersion = errors.New("reservation document has a newer schema version")
+
+// migrations[v] migrates a document of schema version v+1 to v+2.
+var migrations = []func(networkConfig *NetworkConfig) error{
+	// all additions of version 2 are optional
+	func(networkConfig *NetworkConfig) error {
+		if networkConfig.Subnets == nil {
+			networkConfig.Subnets = make(map[string]string)
+		}
+		return nil
+	},
+	// the history starts with the migration, former changes are unknown
+	func(networkConfig *NetworkConfig) error {
+		return nil
+	},
+	// pools without quarantine_period release subnets immediately, as before
+	func(networkConfig *NetworkConfig) error {
+		return nil
+	},
+	// reservations without lease never expire, as before
+	func(networkConfig *NetworkConfig) error {
+		return nil
+	},
+	// longer histories are trimmed to DefaultHistoryLimit by the write of the migrated document
+	func(networkConfig *NetworkConfig) error {
+		return nil
+	},
+}
+
+// Migrate upgrades networkConfig to CurrentSchemaVersion. Documents of a newer schema version are refused, as writing
+// them would drop the content unknown to this version.
+func Migrate(networkConfig *NetworkConfig) error {
+	if networkConfig.SchemaVersion == 0 {
+		networkConfig.SchemaVersion = 1
+	}
+	if networkConfig.SchemaVersion > CurrentSchemaVersion {
+		return fmt.Errorf("%w: the document has schema version %d, but this provider supports up to %d. Please upgrade the provider!", ErrNewerSchemaVersion, networkConfig.SchemaVersion, CurrentSchemaVersion)
+	}
+	for networkConfig.SchemaVersion < CurrentSchemaVersion {
+		if err := migrations[networkConfig.SchemaVersion-1](networkConfig); err != nil {
+			return fmt.Errorf("Failed to migrate the reservation document from schema version %d: %w", networkConfig.SchemaVersion, err)
+		}
+		networkConfig.SchemaVersion++
+	}
+	return nil
+}
+
+func migrateBefore(modify func(networkConfig *NetworkConfig) error) func(networkConfig *NetworkConfig) error {
+	return func(networkConfig *NetworkConfig) error {
+		if err := Migrate(networkConfig); err != nil {
+			return err
+		}
+		return modify(networkConfig)
+	}
+}
@@ -0,0 +1,52 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateMigratesUnversionedDocument(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(directory, "cidr-reservation"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(directory, DocumentName("10.116.0.0/14")), []byte(`{"subnets":{"first":"10.116.0.0/24"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := NewLocalFactory(directory)
+	err = Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		networkConfig.Subnets["second"] = "10.116.1.0/24"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig, err := factory("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if networkConfig.SchemaVersion != CurrentSchemaVersion || len(networkConfig.Subnets) != 2 {
+		t.Fatalf("Expected a migrated document with both subnets, got %+v", networkConfig)
+	}
+}
+
+func TestUpdateRefusesNewerSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	factory := NewLocalFactory(t.TempDir())
+	newer := &NetworkConfig{SchemaVersion: CurrentSchemaVersion + 1, Subnets: map[string]string{"first": "10.116.0.0/24"}}
+	if err := factory("10.116.0.0/14").WriteRemote(newer, ctx); err != nil {
+		t.Fatal(err)
+	}
+	err := Update(ctx, factory("10.116.0.0/14"), func(networkConfig *NetworkConfig) error {
+		t.Fatal("modify must not be called for documents of a newer schema version")
+		return nil
+	})
+	if !errors.Is(err, ErrNewerSchemaVersion) {
+		t.Fatalf("Expected ErrNewerSchemaVersion, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+)
+
+// dataSourceBaseCidr lists all reservations of a base cidr range together with its free space.
+func dataSourceBaseCidr() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceBaseCidrRead,
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"reservations": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"excluded_ranges": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"quarantined_ranges": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"child_pools": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"parent_base_cidr": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			// address counts are strings, as they exceed 64 bit for IPv6 base cidr ranges
+			"total_addresses": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"used_addresses": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"free_netmasks": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceBaseCidrRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	remoteConnector := newRemoteConnector(data, m)
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if errors.Is(err, connector.ErrNotExist) {
+		networkConfig = &connector.NetworkConfig{Subnets: make(map[string]string)}
+	} else if err != nil {
+		return diag.Errorf("Failed to read the reservations of %s: %s", remoteConnector.GetBaseCidrRange(), err)
+	}
+	occupied := networkConfig.OccupiedSubnets()
+	usage, err := cidrCalculator.GetUsage(&occupied, remoteConnector.GetBaseCidrRange())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(fmt.Sprintf("%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange()))
+	if err := data.Set("reservations", networkConfig.Subnets); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("excluded_ranges", networkConfig.Exclusions); err != nil {
+		return diag.FromErr(err)
+	}
+	quarantined := make(map[string]string)
+	for _, tombstone := range networkConfig.ActiveTombstones() {
+		quarantined[tombstone.Cidr] = tombstone.Until
+	}
+	if err := data.Set("quarantined_ranges", quarantined); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("child_pools", networkConfig.ChildPools); err != nil {
+		return diag.FromErr(err)
+	}
+	parentBaseCidr := ""
+	if networkConfig.Parent != nil && !networkConfig.Parent.Released {
+		parentBaseCidr = networkConfig.Parent.BaseCidr
+	}
+	if err := data.Set("parent_base_cidr", parentBaseCidr); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("total_addresses", usage.TotalAddresses.String()); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("used_addresses", usage.UsedAddresses.String()); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("free_netmasks", usage.FreeNetmasks); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
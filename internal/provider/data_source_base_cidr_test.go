@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"reflect"
+	"testing"
+)
+
+func TestDataSourceBaseCidrListsReservationsAndFreeSpace(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 16), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := schema.TestResourceDataRaw(t, dataSourceBaseCidr().Schema, map[string]interface{}{
+		"base_cidr": "10.116.0.0/14",
+	})
+	if diags := dataSourceBaseCidrRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !reflect.DeepEqual(data.Get("reservations"), map[string]interface{}{"first": "10.116.0.0/16"}) {
+		t.Fatalf("Unexpected reservations %v", data.Get("reservations"))
+	}
+	if data.Get("total_addresses") != "262144" || data.Get("used_addresses") != "65536" {
+		t.Fatalf("Unexpected address counts total %s, used %s", data.Get("total_addresses"), data.Get("used_addresses"))
+	}
+	if !reflect.DeepEqual(data.Get("free_netmasks"), []interface{}{"10.117.0.0/16", "10.118.0.0/15"}) {
+		t.Fatalf("Unexpected free netmasks %v", data.Get("free_netmasks"))
+	}
+}
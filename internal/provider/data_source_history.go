@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
+)
+
+// dataSourceHistory lists the recorded changes of the reservations of a base cidr range, optionally only those of a
+// netmask_id or overlapping a cidr range.
+func dataSourceHistory() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceHistoryRead,
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"netmask_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cidr": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"events": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"timestamp":      {Type: schema.TypeString, Computed: true},
+						"action":         {Type: schema.TypeString, Computed: true},
+						"netmask_id":     {Type: schema.TypeString, Computed: true},
+						"old_netmask_id": {Type: schema.TypeString, Computed: true},
+						"old_cidr":       {Type: schema.TypeString, Computed: true},
+						"new_cidr":       {Type: schema.TypeString, Computed: true},
+						"caller":         {Type: schema.TypeString, Computed: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceHistoryRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	remoteConnector := newRemoteConnector(data, m)
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if errors.Is(err, connector.ErrNotExist) {
+		networkConfig = &connector.NetworkConfig{}
+	} else if err != nil {
+		return diag.Errorf("Failed to read the history of %s: %s", remoteConnector.GetBaseCidrRange(), err)
+	}
+	netmaskId := data.Get("netmask_id").(string)
+	var cidr *net.IPNet
+	if requested := data.Get("cidr").(string); requested != "" {
+		if _, cidr, err = net.ParseCIDR(requested); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	events := make([]interface{}, 0)
+	for _, event := range connector.FilterHistory(networkConfig.History, netmaskId, cidr) {
+		events = append(events, map[string]interface{}{
+			"timestamp":      event.Timestamp,
+			"action":         event.Action,
+			"netmask_id":     event.NetmaskId,
+			"old_netmask_id": event.OldNetmaskId,
+			"old_cidr":       event.OldCidr,
+			"new_cidr":       event.NewCidr,
+			"caller":         event.Caller,
+		})
+	}
+	data.SetId(fmt.Sprintf("%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange()))
+	if err := data.Set("events", events); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
@@ -0,0 +1,45 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+)
+
+func TestDataSourceHistoryListsChangesOfCidr(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	meta.caller = "alice"
+	first := newNetworkRequest(t, "first", 22)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerDelete(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	meta.caller = "bob"
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "second", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "third", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := schema.TestResourceDataRaw(t, dataSourceHistory().Schema, map[string]interface{}{
+		"base_cidr": "10.116.0.0/14",
+		"cidr":      "10.116.0.0/24",
+	})
+	if diags := dataSourceHistoryRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	events := data.Get("events").([]interface{})
+	if len(events) != 3 {
+		t.Fatalf("Expected the allocation and release of first and the allocation of second, got %v", events)
+	}
+	expected := [][]string{{"allocate", "first", "alice"}, {"release", "first", "alice"}, {"allocate", "second", "bob"}}
+	for i, event := range events {
+		event := event.(map[string]interface{})
+		if event["action"] != expected[i][0] || event["netmask_id"] != expected[i][1] || event["caller"] != expected[i][2] {
+			t.Fatalf("Unexpected event %d: %v", i, event)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"strconv"
+	"strings"
+)
+
+// dataSourceNetworkRequest reads a reservation owned by another Terraform state without managing it.
+func dataSourceNetworkRequest() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceNetworkRequestRead,
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"netmask_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"netmask": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"prefix_length": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"owner": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"labels": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceNetworkRequestRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	remoteConnector := newRemoteConnector(data, m)
+	netmaskId := data.Get("netmask_id").(string)
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		return diag.Errorf("Failed to read the reservations of %s: %s", remoteConnector.GetBaseCidrRange(), err)
+	}
+	netmask, contains := networkConfig.Subnets[netmaskId]
+	if !contains {
+		return diag.Errorf("Netmask with id %s does not exist in %s!", netmaskId, remoteConnector.GetBaseCidrRange())
+	}
+	prefixLength, err := strconv.Atoi(strings.Split(netmask, "/")[1])
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), netmaskId))
+	if err := data.Set("netmask", netmask); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("prefix_length", prefixLength); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := setReservationMetadata(data, networkConfig.Metadata[netmaskId]); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
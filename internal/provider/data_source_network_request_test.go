@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+)
+
+func TestDataSourceNetworkRequestReadsReservation(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := schema.TestResourceDataRaw(t, dataSourceNetworkRequest().Schema, map[string]interface{}{
+		"base_cidr":  "10.116.0.0/14",
+		"netmask_id": "first",
+	})
+	if diags := dataSourceNetworkRequestRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("netmask") != "10.116.0.0/24" || data.Get("prefix_length") != 24 {
+		t.Fatalf("Unexpected netmask %s or prefix_length %d", data.Get("netmask"), data.Get("prefix_length"))
+	}
+}
+
+func TestDataSourceNetworkRequestFailsForUnknownNetmaskId(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := schema.TestResourceDataRaw(t, dataSourceNetworkRequest().Schema, map[string]interface{}{
+		"base_cidr":  "10.116.0.0/14",
+		"netmask_id": "unknown",
+	})
+	if diags := dataSourceNetworkRequestRead(ctx, data, meta); !diags.HasError() {
+		t.Fatal("Reading an unknown netmask_id should fail!")
+	}
+}
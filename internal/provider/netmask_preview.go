@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+)
+
+// previewNetmask shows the netmask a new or resized network request is expected to get in the plan as
+// expected_netmask, so reviewers see which range is used. For resized ones it also shows in resize_action, whether
+// they are resized in place or relocated. Only fixed results, requested cidr ranges and resizes in place, are planned
+// as netmask, which apply has to reserve. The next free range may be taken by another apply in the meantime, e.g. of
+// another network request planned together, so its netmask stays unknown, unless require_expected_netmask is set.
+// Then it is planned as netmask as well and apply fails, if it has been taken. The preview is best effort: if the
+// reservation document cannot be read or no range can be calculated, both stay unknown and apply reports the actual
+// problem. Only a resize, which is neither possible in place nor allowed to relocate, already fails the plan.
+func previewNetmask(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	resize := diff.Id() != "" && diff.HasChange("prefix_length")
+	if diff.Id() != "" && !resize {
+		return nil
+	}
+	for _, key := range []string{"base_cidr", "netmask_id", "prefix_length", "requested_cidr", "allocation_strategy", "allow_relocation", "require_expected_netmask"} {
+		if !diff.NewValueKnown(key) {
+			return unknownNetmask(diff, resize)
+		}
+	}
+	baseCidr := diff.Get("base_cidr").(string)
+	prefixLength := diff.Get("prefix_length").(int)
+	strategy := cidrCalculator.Strategy(diff.Get("allocation_strategy").(string))
+	requestedCidr := diff.Get("requested_cidr").(string)
+	allowRelocation := diff.Get("allow_relocation").(bool)
+	var netmask, resizeAction string
+	var resizeErr error
+	err := connector.DryRun(ctx, m.(*providerConfig).newConnector(baseCidr), func(networkConfig *connector.NetworkConfig) error {
+		occupied := networkConfig.OccupiedSubnets()
+		var err error
+		switch {
+		case resize:
+			// the reservation is looked up by the state, as the configuration may rename it
+			netmaskId, _ := diff.GetChange("netmask_id")
+			netmask, resizeAction, resizeErr = resizeNetmask(networkConfig, netmaskId.(string), prefixLength, baseCidr, strategy, allowRelocation)
+			return resizeErr
+		case requestedCidr != "":
+			netmask, err = cidrCalculator.VerifyRequestedNetmask(&occupied, requestedCidr, baseCidr)
+		default:
+			netmask, err = cidrCalculator.NextNetmask(&occupied, prefixLength, baseCidr, strategy)
+		}
+		return err
+	})
+	if resizeErr != nil && !allowRelocation {
+		return resizeErr
+	}
+	if err != nil {
+		tflog.Warn(ctx, "Failed to preview the netmask", map[string]interface{}{"base_cidr": baseCidr, "error": err.Error()})
+		return unknownNetmask(diff, resize)
+	}
+	if resize {
+		if err := diff.SetNew("resize_action", resizeAction); err != nil {
+			return err
+		}
+	}
+	if err := diff.SetNew("expected_netmask", netmask); err != nil {
+		return err
+	}
+	if requestedCidr != "" || resizeAction == resizeInPlace || diff.Get("require_expected_netmask").(bool) {
+		return diff.SetNew("netmask", netmask)
+	}
+	return diff.SetNewComputed("netmask")
+}
+
+// unknownNetmask marks the netmask of a resized network request as unknown, which would show the current one otherwise.
+func unknownNetmask(diff *schema.ResourceDiff, resize bool) error {
+	if !resize {
+		return nil
+	}
+	for _, key := range []string{"netmask", "expected_netmask", "resize_action"} {
+		if err := diff.SetNewComputed(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plannedNetmask returns the netmask shown in the plan by previewNetmask, or "" if there is none.
+func plannedNetmask(data *schema.ResourceData) string {
+	current, planned := data.GetChange("netmask")
+	if current.(string) == planned.(string) {
+		return ""
+	}
+	return planned.(string)
+}
+
+// verifyPlannedNetmask fails, if netmask differs from the planned one, as resources depending on it have been planned
+// with the planned one.
+func verifyPlannedNetmask(planned string, netmask string) error {
+	if planned != "" && planned != netmask {
+		return fmt.Errorf("The plan showed the netmask %s, but the reservations have changed in the meantime and %s would be reserved instead. Please plan again!!!", planned, netmask)
+	}
+	return nil
+}
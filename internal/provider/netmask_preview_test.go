@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"strings"
+	"testing"
+)
+
+func TestPlanPreviewsNextNetmask(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resource := resourceServer()
+	diff, err := resource.Diff(ctx, nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "second",
+		"prefix_length": 24,
+	}), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := diff.Attributes["expected_netmask"]; expected == nil || expected.NewComputed || expected.New != "10.116.1.0/24" {
+		t.Fatalf("Expected the plan to show 10.116.1.0/24, got %+v", expected)
+	}
+	if netmask := diff.Attributes["netmask"]; netmask == nil || !netmask.NewComputed {
+		t.Fatalf("The next free netmask should not be bound by the plan, got %+v", netmask)
+	}
+	second, err := schema.InternalMap(resource.Schema).Data(nil, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if second.Get("netmask") != "10.116.1.0/24" {
+		t.Fatalf("Expected the planned netmask to be reserved, got %s", second.Get("netmask"))
+	}
+}
+
+func TestPlanPreviewsResize(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	state := &terraform.InstanceState{ID: first.Id(), Attributes: map[string]string{
+		"id":            first.Id(),
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "first",
+		"prefix_length": "24",
+		"netmask":       "10.116.0.0/24",
+	}}
+	config := map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "first",
+		"prefix_length": 22,
+	}
+	diff, err := resourceServer().Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask := diff.Attributes["netmask"]; netmask == nil || netmask.New != "10.116.0.0/22" || diff.Attributes["resize_action"].New != "in_place" {
+		t.Fatalf("Expected the plan to show 10.116.0.0/22 resized in place, got %+v and %+v", netmask, diff.Attributes["resize_action"])
+	}
+
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "second", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, err := resourceServer().Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta); err == nil || !strings.Contains(err.Error(), "allow_relocation") {
+		t.Fatalf("Expected the plan to fail, as the range cannot grow in place, got %v", err)
+	}
+	config["allow_relocation"] = true
+	diff, err = resourceServer().Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := diff.Attributes["expected_netmask"]; expected == nil || expected.New != "10.116.4.0/22" || diff.Attributes["resize_action"].New != "relocate" || !diff.Attributes["netmask"].NewComputed {
+		t.Fatalf("Expected the plan to show 10.116.4.0/22 relocated, got %+v and %+v", expected, diff.Attributes["resize_action"])
+	}
+
+	config["netmask_id"] = "renamed"
+	config["prefix_length"] = 24
+	diff, err = resourceServer().Diff(ctx, state, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask, contains := diff.Attributes["netmask"]; contains && netmask.Old != netmask.New {
+		t.Fatalf("Renaming should keep the netmask, got %+v", netmask)
+	}
+}
+
+// planCreate plans a new network request like terraform plan and returns the data terraform apply creates it with.
+func planCreate(t *testing.T, meta *providerConfig, config map[string]interface{}) (*terraform.InstanceDiff, *schema.ResourceData) {
+	resource := resourceServer()
+	diff, err := resource.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := schema.InternalMap(resource.Schema).Data(nil, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diff, data
+}
+
+func TestRequestsPlannedTogetherCanBeApplied(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	firstDiff, first := planCreate(t, meta, map[string]interface{}{"base_cidr": "10.116.0.0/14", "netmask_id": "first", "prefix_length": 24})
+	secondDiff, second := planCreate(t, meta, map[string]interface{}{"base_cidr": "10.116.0.0/14", "netmask_id": "second", "prefix_length": 24})
+	if firstDiff.Attributes["expected_netmask"].New != "10.116.0.0/24" || secondDiff.Attributes["expected_netmask"].New != "10.116.0.0/24" {
+		t.Fatalf("Both plans should expect the first free range, got %+v and %+v", firstDiff.Attributes["expected_netmask"], secondDiff.Attributes["expected_netmask"])
+	}
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if first.Get("netmask") != "10.116.0.0/24" || second.Get("netmask") != "10.116.1.0/24" || second.Get("expected_netmask") != "10.116.1.0/24" {
+		t.Fatalf("Unexpected netmasks %s and %s", first.Get("netmask"), second.Get("netmask"))
+	}
+}
+
+func TestRequiredExpectedNetmaskFailsIfTakenInTheMeantime(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	config := func(netmaskId string) map[string]interface{} {
+		return map[string]interface{}{"base_cidr": "10.116.0.0/14", "netmask_id": netmaskId, "prefix_length": 24, "require_expected_netmask": true}
+	}
+	firstDiff, first := planCreate(t, meta, config("first"))
+	_, second := planCreate(t, meta, config("second"))
+	if netmask := firstDiff.Attributes["netmask"]; netmask == nil || netmask.NewComputed || netmask.New != "10.116.0.0/24" {
+		t.Fatalf("Expected the plan to bind the expected netmask 10.116.0.0/24, got %+v", netmask)
+	}
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerCreate(ctx, second, meta); !diags.HasError() || !strings.Contains(diags[0].Summary, "Please plan again") {
+		t.Fatalf("Reserving another range than the expected one should fail, got %s", second.Get("netmask"))
+	}
+	networkConfig, err := meta.newConnector("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, contains := networkConfig.Subnets["second"]; contains {
+		t.Fatal("Nothing should be reserved for second")
+	}
+}
+
+func TestPlanBindsRequestedCidr(t *testing.T) {
+	diff, _ := planCreate(t, newMemoryMeta(), map[string]interface{}{"base_cidr": "10.116.0.0/14", "netmask_id": "first", "prefix_length": 24, "requested_cidr": "10.116.3.0/24"})
+	if netmask := diff.Attributes["netmask"]; netmask == nil || netmask.NewComputed || netmask.New != "10.116.3.0/24" {
+		t.Fatalf("Expected the plan to show the requested cidr 10.116.3.0/24, got %+v", netmask)
+	}
+}
+
+func TestUpdateFailsIfPlannedResizeInPlaceHasBeenTaken(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resource := resourceServer()
+	diff, err := resource.Diff(ctx, first.State(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"base_cidr":        "10.116.0.0/14",
+		"netmask_id":       "first",
+		"prefix_length":    23,
+		"allow_relocation": true,
+	}), meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if netmask := diff.Attributes["netmask"]; netmask == nil || netmask.New != "10.116.0.0/23" {
+		t.Fatalf("Expected the plan to grow first in place to 10.116.0.0/23, got %+v", netmask)
+	}
+	resized, err := schema.InternalMap(resource.Schema).Data(first.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "concurrent", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerUpdate(ctx, resized, meta); !diags.HasError() {
+		t.Fatalf("Relocating a reservation planned to grow in place should fail, got %s", resized.Get("netmask"))
+	}
+}
@@ -4,19 +4,102 @@ import (
 	"context"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
 )
 
+// providerConfig is handed to all resources as meta and determines where the reservation documents are stored.
+type providerConfig struct {
+	newConnector connector.Factory
+	// caller is recorded in the history of the reservation documents for all changes made by this provider.
+	caller string
+}
+
 func New(version string) func() *schema.Provider {
 	return func() *schema.Provider {
 		return &schema.Provider{
 			Schema: map[string]*schema.Schema{
+				"backend": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "gcs",
+					ValidateFunc: validation.StringInSlice(connector.Backends, false),
+				},
 				"reservator_bucket": {
 					Type:     schema.TypeString,
-					Required: true,
+					Optional: true,
+				},
+				"local_directory": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"s3_region": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"s3_endpoint": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"s3_use_path_style": {
+					Type:     schema.TypeBool,
+					Optional: true,
+				},
+				"azure_connection_string": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					DefaultFunc: schema.EnvDefaultFunc("AZURE_STORAGE_CONNECTION_STRING", nil),
+				},
+				"azure_account_url": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"consul_address": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"consul_token": {
+					Type:      schema.TypeString,
+					Optional:  true,
+					Sensitive: true,
+				},
+				"consul_key_prefix": {
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "cidr-reservator",
+				},
+				"postgres_connection_string": {
+					Type:      schema.TypeString,
+					Optional:  true,
+					Sensitive: true,
+				},
+				"http_address": {
+					Type:     schema.TypeString,
+					Optional: true,
+				},
+				"http_token": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					DefaultFunc: schema.EnvDefaultFunc("CIDR_RESERVATOR_HTTP_TOKEN", nil),
+				},
+				"caller": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("CIDR_RESERVATOR_CALLER", nil),
 				},
 			},
 			ResourcesMap: map[string]*schema.Resource{
-				"cidr-reservator_network_request": resourceServer(),
+				"cidr-reservator_network_request":     resourceServer(),
+				"cidr-reservator_network_request_set": resourceNetworkRequestSet(),
+				"cidr-reservator_exclusion":           resourceExclusion(),
+				"cidr-reservator_pool":                resourcePool(),
+			},
+			DataSourcesMap: map[string]*schema.Resource{
+				"cidr-reservator_network_request": dataSourceNetworkRequest(),
+				"cidr-reservator_base_cidr":       dataSourceBaseCidr(),
+				"cidr-reservator_history":         dataSourceHistory(),
 			},
 			ConfigureContextFunc: providerConfigure,
 		}
@@ -24,11 +107,37 @@ func New(version string) func() *schema.Provider {
 }
 
 func providerConfigure(ctx context.Context, data *schema.ResourceData) (interface{}, diag.Diagnostics) {
-	cidrReservatorBucket := data.Get("reservator_bucket").(string)
 	var diags diag.Diagnostics
-	if cidrReservatorBucket == "" {
-		return nil, diag.Errorf("reservator_bucket is not set!")
+	factory, err := connector.NewFactory(ctx, connector.Options{
+		Backend:        data.Get("backend").(string),
+		Bucket:         data.Get("reservator_bucket").(string),
+		LocalDirectory: data.Get("local_directory").(string),
+		S3: connector.S3Options{
+			Region:       data.Get("s3_region").(string),
+			Endpoint:     data.Get("s3_endpoint").(string),
+			UsePathStyle: data.Get("s3_use_path_style").(bool),
+		},
+		Azure: connector.AzureOptions{
+			ConnectionString: data.Get("azure_connection_string").(string),
+			AccountUrl:       data.Get("azure_account_url").(string),
+		},
+		ConsulKeyPrefix: data.Get("consul_key_prefix").(string),
+		Consul: connector.ConsulOptions{
+			Address: data.Get("consul_address").(string),
+			Token:   data.Get("consul_token").(string),
+		},
+		PostgresConnectionString: data.Get("postgres_connection_string").(string),
+		Http: connector.HttpOptions{
+			Address: data.Get("http_address").(string),
+			Token:   data.Get("http_token").(string),
+		},
+	})
+	if err != nil {
+		return nil, diag.FromErr(err)
 	}
-
-	return cidrReservatorBucket, diags
+	caller := data.Get("caller").(string)
+	if caller == "" {
+		caller = connector.DefaultCaller()
+	}
+	return &providerConfig{newConnector: factory, caller: caller}, diags
 }
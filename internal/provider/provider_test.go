@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"testing"
+)
+
+func TestProvider(t *testing.T) {
+	if err := New("dev")().InternalValidate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviderConfigureLocalBackend(t *testing.T) {
+	provider := New("dev")()
+	diags := provider.Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{
+		"backend":         "local",
+		"local_directory": t.TempDir(),
+	}))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, ok := provider.Meta().(*providerConfig); !ok {
+		t.Fatalf("Unexpected provider meta %v", provider.Meta())
+	}
+}
+
+func TestProviderConfigureRequiresBucket(t *testing.T) {
+	diags := New("dev")().Configure(context.Background(), terraform.NewResourceConfigRaw(map[string]interface{}{}))
+	if !diags.HasError() {
+		t.Fatal("The gcs backend should require reservator_bucket!")
+	}
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+)
+
+// resourceExclusion marks a range within a base cidr range as off-limits for the allocator, without reserving it
+// as a subnet.
+func resourceExclusion() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceExclusionCreate,
+		ReadContext:   resourceExclusionRead,
+		DeleteContext: resourceExclusionDelete,
+		CustomizeDiff: rejectDocumentChanges("managing exclusions", "base_cidr", "exclusion_id", "cidr"),
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"exclusion_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importExclusionState,
+		},
+	}
+}
+
+func resourceExclusionCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	err := retry(innerResourceExclusionCreate(ctx, data, m))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func innerResourceExclusionCreate(ctx context.Context, data *schema.ResourceData, m interface{}) func() error {
+	return func() error {
+		remoteConnector := newRemoteConnector(data, m)
+		exclusionId := data.Get("exclusion_id").(string)
+		var excluded string
+		err := update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+			if _, contains := networkConfig.Exclusions[exclusionId]; contains {
+				return fmt.Errorf("The exclusionId %s already exists, but does not belong to your Terraform state!!!", exclusionId)
+			}
+			occupied := networkConfig.OccupiedSubnets()
+			var err error
+			excluded, err = cidrCalculator.VerifyRequestedNetmask(&occupied, data.Get("cidr").(string), remoteConnector.GetBaseCidrRange())
+			if err != nil {
+				return err
+			}
+			if networkConfig.Exclusions == nil {
+				networkConfig.Exclusions = make(map[string]string)
+			}
+			networkConfig.Exclusions[exclusionId] = excluded
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), exclusionId))
+		return data.Set("cidr", excluded)
+	}
+}
+
+func resourceExclusionRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	err := readExclusion(ctx, data, newRemoteConnector(data, m), data.Get("exclusion_id").(string))
+	if errors.As(err, &exclusionNotExistError{}) || errors.Is(err, connector.ErrNotExist) {
+		data.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func importExclusionState(ctx context.Context, data *schema.ResourceData, i interface{}) ([]*schema.ResourceData, error) {
+	baseCidr, exclusionId, err := parseImportId(data.Id(), i)
+	if err != nil {
+		return nil, err
+	}
+	if err := readExclusion(ctx, data, i.(*providerConfig).newConnector(baseCidr), exclusionId); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{data}, nil
+}
+
+// exclusionNotExistError is returned, if an exclusion has been removed outside of Terraform.
+type exclusionNotExistError struct {
+	exclusionId string
+}
+
+func (e exclusionNotExistError) Error() string {
+	return fmt.Sprintf("Exclusion with id %s does not exist!", e.exclusionId)
+}
+
+// readExclusion fills data with the stored exclusion of exclusionId.
+func readExclusion(ctx context.Context, data *schema.ResourceData, remoteConnector connector.Connector, exclusionId string) error {
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		return err
+	}
+	excluded, contains := networkConfig.Exclusions[exclusionId]
+	if !contains {
+		return exclusionNotExistError{exclusionId}
+	}
+	data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), exclusionId))
+	if err := data.Set("base_cidr", remoteConnector.GetBaseCidrRange()); err != nil {
+		return err
+	}
+	if err := data.Set("exclusion_id", exclusionId); err != nil {
+		return err
+	}
+	return data.Set("cidr", excluded)
+}
+
+func resourceExclusionDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	exclusionId := data.Get("exclusion_id").(string)
+	err := retry(func() error {
+		return update(ctx, m, newRemoteConnector(data, m), func(networkConfig *connector.NetworkConfig) error {
+			delete(networkConfig.Exclusions, exclusionId)
+			return nil
+		})
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
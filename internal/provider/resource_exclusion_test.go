@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"testing"
+)
+
+func newExclusion(t *testing.T, exclusionId string, cidr string) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceExclusion().Schema, map[string]interface{}{
+		"base_cidr":    "10.116.0.0/14",
+		"exclusion_id": exclusionId,
+		"cidr":         cidr,
+	})
+}
+
+func TestExclusionIsNeverHandedOut(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceExclusionCreate(ctx, newExclusion(t, "onprem", "10.116.0.0/23"), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("netmask") != "10.116.2.0/24" {
+		t.Fatalf("The excluded range must not be handed out, got %s", data.Get("netmask"))
+	}
+	baseCidr := schema.TestResourceDataRaw(t, dataSourceBaseCidr().Schema, map[string]interface{}{"base_cidr": "10.116.0.0/14"})
+	if diags := dataSourceBaseCidrRead(ctx, baseCidr, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if len(baseCidr.Get("reservations").(map[string]interface{})) != 1 || baseCidr.Get("excluded_ranges").(map[string]interface{})["onprem"] != "10.116.0.0/23" {
+		t.Fatalf("Exclusions must not be listed as reservations, got %v and %v", baseCidr.Get("reservations"), baseCidr.Get("excluded_ranges"))
+	}
+}
+
+func TestExclusionConflictsWithReservation(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceExclusionCreate(ctx, newExclusion(t, "onprem", "10.116.0.0/23"), meta); !diags.HasError() {
+		t.Fatal("Excluding an already reserved range should fail!")
+	}
+}
+
+func TestExclusionDeleteReleasesRange(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	exclusion := newExclusion(t, "onprem", "10.116.0.0/24")
+	if diags := resourceExclusionCreate(ctx, exclusion, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceExclusionRead(ctx, exclusion, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceExclusionDelete(ctx, exclusion, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("netmask") != "10.116.0.0/24" {
+		t.Fatalf("The released exclusion should be free again, got %s", data.Get("netmask"))
+	}
+}
+
+func TestExclusionRemovedOutsideTerraformIsDropped(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	exclusion := newExclusion(t, "onprem", "10.116.0.0/24")
+	if diags := resourceExclusionRead(ctx, exclusion, meta); diags.HasError() || exclusion.Id() != "" {
+		t.Fatalf("A missing reservation document should drop the exclusion, got %v", diags)
+	}
+	if diags := resourceExclusionCreate(ctx, exclusion, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	err := connector.Update(ctx, meta.newConnector("10.116.0.0/14"), func(networkConfig *connector.NetworkConfig) error {
+		delete(networkConfig.Exclusions, "onprem")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceExclusionRead(ctx, exclusion, meta); diags.HasError() || exclusion.Id() != "" {
+		t.Fatalf("A removed exclusion should be dropped from the state, got %v", diags)
+	}
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// resourceNetworkRequestSet reserves several ranges of a base cidr range, e.g. the node, pod and service ranges of a
+// GKE cluster, within one write of the reservation document, so either all of them are reserved or none.
+func resourceNetworkRequestSet() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceNetworkRequestSetCreate,
+		ReadContext:   resourceNetworkRequestSetRead,
+		UpdateContext: resourceNetworkRequestSetUpdate,
+		DeleteContext: resourceNetworkRequestSetDelete,
+		CustomizeDiff: rejectDocumentChanges("managing network request sets", "base_cidr", "ranges", "allocation_strategy", "allow_relocation"),
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			// prefix lengths by netmask_id
+			"ranges": {
+				Type:     schema.TypeMap,
+				Required: true,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+			},
+			"allocation_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice(cidrCalculator.StrategyNames(), false),
+			},
+			"netmasks": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"allow_relocation": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importNetworkRequestSetState,
+		},
+	}
+}
+
+func resourceNetworkRequestSetCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	err := retry(innerResourceNetworkRequestSetUpdate(ctx, data, m, nil))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceNetworkRequestSetRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	_, err := readNetworkRequestSet(ctx, data, newRemoteConnector(data, m), netmaskIdsOf(data.Get("ranges")))
+	if errors.As(err, &netmaskNotExistError{}) || errors.Is(err, connector.ErrNotExist) {
+		data.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourceNetworkRequestSetUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	reserved, _ := data.GetChange("ranges")
+	err := retry(innerResourceNetworkRequestSetUpdate(ctx, data, m, netmaskIdsOf(reserved)))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+// innerResourceNetworkRequestSetUpdate releases the reserved netmask ids no longer within ranges, resizes those with a
+// changed prefix length like resizeNetmask and reserves the new ones, all within one write of the reservation document.
+func innerResourceNetworkRequestSetUpdate(ctx context.Context, data *schema.ResourceData, m interface{}, reserved []string) func() error {
+	return func() error {
+		remoteConnector := newRemoteConnector(data, m)
+		strategy := cidrCalculator.Strategy(data.Get("allocation_strategy").(string))
+		allowRelocation := data.Get("allow_relocation").(bool)
+		ranges := make(map[string]int)
+		for netmaskId, prefixLength := range data.Get("ranges").(map[string]interface{}) {
+			if strings.ContainsAny(netmaskId, ":,") {
+				return fmt.Errorf("The netmaskId %s must neither contain ':' nor ','!", netmaskId)
+			}
+			ranges[netmaskId] = prefixLength.(int)
+		}
+		if len(ranges) == 0 {
+			return fmt.Errorf("ranges must contain at least one netmaskId!")
+		}
+		netmasks := make(map[string]string)
+		err := update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+			if err := networkConfig.VerifyChildPool(ctx, m.(*providerConfig).newConnector, remoteConnector.GetBaseCidrRange()); err != nil {
+				return err
+			}
+			isReserved := make(map[string]bool, len(reserved))
+			for _, netmaskId := range reserved {
+				isReserved[netmaskId] = true
+				if _, contains := ranges[netmaskId]; !contains {
+					if networkConfig.ChildPools[netmaskId] != "" {
+						return fmt.Errorf("The netmaskId %s has been promoted into a child pool and has to be released by its network request!", netmaskId)
+					}
+					networkConfig.Release(netmaskId)
+				}
+			}
+			var toResize, toReserve []string
+			for netmaskId, prefixLength := range ranges {
+				subnet, contains := networkConfig.Subnets[netmaskId]
+				if contains && !isReserved[netmaskId] {
+					return fmt.Errorf("The netmaskId %s already exists, but does not belong to your Terraform state!!!", netmaskId)
+				}
+				if contains {
+					if _, ipNet, err := net.ParseCIDR(subnet); err == nil {
+						if currentPrefixLength, _ := ipNet.Mask.Size(); currentPrefixLength == prefixLength {
+							netmasks[netmaskId] = subnet
+							continue
+						}
+					}
+					if networkConfig.ChildPools[netmaskId] != "" {
+						return fmt.Errorf("The netmaskId %s has been promoted into a child pool, which can neither be renamed nor resized!", netmaskId)
+					}
+					toResize = append(toResize, netmaskId)
+					continue
+				}
+				toReserve = append(toReserve, netmaskId)
+			}
+			// resized before reserving the new ones, which could take the ranges needed to grow in place otherwise
+			sort.Strings(toResize)
+			for _, netmaskId := range toResize {
+				netmask, _, err := resizeNetmask(networkConfig, netmaskId, ranges[netmaskId], remoteConnector.GetBaseCidrRange(), strategy, allowRelocation)
+				if err != nil {
+					return fmt.Errorf("Failed to resize %s, so none of the ranges is changed: %s", netmaskId, err)
+				}
+				networkConfig.Subnets[netmaskId] = netmask
+				netmasks[netmaskId] = netmask
+			}
+			// the biggest ranges first, so the smaller ones fill the gaps next to them
+			sort.Slice(toReserve, func(i, j int) bool {
+				if ranges[toReserve[i]] != ranges[toReserve[j]] {
+					return ranges[toReserve[i]] < ranges[toReserve[j]]
+				}
+				return toReserve[i] < toReserve[j]
+			})
+			for _, netmaskId := range toReserve {
+				occupied := networkConfig.OccupiedSubnets()
+				nextNetmask, err := cidrCalculator.NextNetmask(&occupied, ranges[netmaskId], remoteConnector.GetBaseCidrRange(), strategy)
+				if err != nil {
+					return fmt.Errorf("Failed to reserve %s, so none of the ranges is reserved: %s", netmaskId, err)
+				}
+				networkConfig.Subnets[netmaskId] = nextNetmask
+				netmasks[netmaskId] = nextNetmask
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		data.SetId(networkRequestSetId(remoteConnector, ranges))
+		return data.Set("netmasks", netmasks)
+	}
+}
+
+func resourceNetworkRequestSetDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	err := retry(func() error {
+		return update(ctx, m, newRemoteConnector(data, m), func(networkConfig *connector.NetworkConfig) error {
+			for netmaskId := range data.Get("ranges").(map[string]interface{}) {
+				if networkConfig.ChildPools[netmaskId] != "" {
+					return fmt.Errorf("The netmaskId %s has been promoted into a child pool and has to be released by its network request!", netmaskId)
+				}
+				networkConfig.Release(netmaskId)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+// importNetworkRequestSetState adopts existing reservations by an id of the form location:baseCidr:netmaskId,...
+func importNetworkRequestSetState(ctx context.Context, data *schema.ResourceData, i interface{}) ([]*schema.ResourceData, error) {
+	baseCidr, netmaskIds, err := parseImportId(data.Id(), i)
+	if err != nil {
+		return nil, err
+	}
+	missing, err := readNetworkRequestSet(ctx, data, i.(*providerConfig).newConnector(baseCidr), strings.Split(netmaskIds, ","))
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, netmaskNotExistError{missing[0]}
+	}
+	return []*schema.ResourceData{data}, nil
+}
+
+// readNetworkRequestSet fills data with the stored reservations of netmaskIds and returns the netmask ids, which have
+// been released outside of Terraform. They are left out of ranges, so the next apply reserves them again. If none of
+// them exists anymore, it fails with netmaskNotExistError.
+func readNetworkRequestSet(ctx context.Context, data *schema.ResourceData, remoteConnector connector.Connector, netmaskIds []string) ([]string, error) {
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make(map[string]int, len(netmaskIds))
+	netmasks := make(map[string]string, len(netmaskIds))
+	var missing []string
+	for _, netmaskId := range netmaskIds {
+		subnet, contains := networkConfig.Subnets[netmaskId]
+		if !contains {
+			missing = append(missing, netmaskId)
+			continue
+		}
+		prefixLength, err := strconv.Atoi(strings.Split(subnet, "/")[1])
+		if err != nil {
+			return nil, err
+		}
+		ranges[netmaskId] = prefixLength
+		netmasks[netmaskId] = subnet
+	}
+	if len(ranges) == 0 && len(missing) > 0 {
+		return missing, netmaskNotExistError{missing[0]}
+	}
+	data.SetId(networkRequestSetId(remoteConnector, ranges))
+	if err := data.Set("base_cidr", remoteConnector.GetBaseCidrRange()); err != nil {
+		return nil, err
+	}
+	if err := data.Set("ranges", ranges); err != nil {
+		return nil, err
+	}
+	return missing, data.Set("netmasks", netmasks)
+}
+
+// netmaskIdsOf returns the netmask ids of ranges.
+func netmaskIdsOf(ranges interface{}) []string {
+	netmaskIds := make([]string, 0)
+	for netmaskId := range ranges.(map[string]interface{}) {
+		netmaskIds = append(netmaskIds, netmaskId)
+	}
+	return netmaskIds
+}
+
+// networkRequestSetId returns an id of the form location:baseCidr:netmaskId,... with the netmask ids sorted.
+func networkRequestSetId(remoteConnector connector.Connector, ranges map[string]int) string {
+	netmaskIds := make([]string, 0, len(ranges))
+	for netmaskId := range ranges {
+		netmaskIds = append(netmaskIds, netmaskId)
+	}
+	sort.Strings(netmaskIds)
+	return fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), strings.Join(netmaskIds, ","))
+}
@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"reflect"
+	"testing"
+)
+
+func newNetworkRequestSet(t *testing.T, ranges map[string]interface{}) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceNetworkRequestSet().Schema, map[string]interface{}{
+		"base_cidr": "10.116.0.0/14",
+		"ranges":    ranges,
+	})
+}
+
+func TestNetworkRequestSetReservesAllRanges(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 16, "services": 20})
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected := map[string]interface{}{"pods": "10.116.0.0/16", "services": "10.117.0.0/20", "nodes": "10.117.16.0/22"}
+	if !reflect.DeepEqual(data.Get("netmasks"), expected) {
+		t.Fatalf("Unexpected netmasks %v", data.Get("netmasks"))
+	}
+	if data.Id() != "memory:10.116.0.0/14:nodes,pods,services" {
+		t.Fatalf("Unexpected id %s", data.Id())
+	}
+	imported := schema.TestResourceDataRaw(t, resourceNetworkRequestSet().Schema, map[string]interface{}{})
+	imported.SetId(data.Id())
+	if _, err := importNetworkRequestSetState(ctx, imported, meta); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported.Get("netmasks"), expected) || imported.Get("ranges").(map[string]interface{})["nodes"] != 22 {
+		t.Fatalf("Unexpected import %v, %v", imported.Get("netmasks"), imported.Get("ranges"))
+	}
+}
+
+func TestNetworkRequestSetReservesNoneIfOneDoesNotFit(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "existing", 15), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 15, "services": 20})
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); !diags.HasError() {
+		t.Fatal("The set should fail, as the pods range does not fit anymore")
+	}
+	networkConfig, err := meta.newConnector("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Subnets) != 1 {
+		t.Fatalf("None of the ranges should be reserved, got %v", networkConfig.Subnets)
+	}
+}
+
+func TestNetworkRequestSetUpdateKeepsUnchangedRanges(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 16})
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	updated := updateData(t, resourceNetworkRequestSet(), data, map[string]interface{}{
+		"base_cidr": "10.116.0.0/14",
+		"ranges":    map[string]interface{}{"pods": 16, "services": 20},
+	})
+	if diags := resourceNetworkRequestSetUpdate(ctx, updated, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected := map[string]interface{}{"pods": "10.116.0.0/16", "services": "10.117.0.0/20"}
+	if !reflect.DeepEqual(updated.Get("netmasks"), expected) {
+		t.Fatalf("Unexpected netmasks %v", updated.Get("netmasks"))
+	}
+	if diags := resourceNetworkRequestSetDelete(ctx, updated, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	networkConfig, err := meta.newConnector("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networkConfig.Subnets) != 0 {
+		t.Fatalf("All ranges should be released, got %v", networkConfig.Subnets)
+	}
+}
+
+func TestNetworkRequestSetResizesInPlaceUnlessRelocationIsAllowed(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 16})
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resize := func(ranges map[string]interface{}, allowRelocation bool) (*schema.ResourceData, diag.Diagnostics) {
+		resized := updateData(t, resourceNetworkRequestSet(), data, map[string]interface{}{
+			"base_cidr":        "10.116.0.0/14",
+			"ranges":           ranges,
+			"allow_relocation": allowRelocation,
+		})
+		diags := resourceNetworkRequestSetUpdate(ctx, resized, meta)
+		if !diags.HasError() {
+			data = resized
+		}
+		return resized, diags
+	}
+	resized, diags := resize(map[string]interface{}{"nodes": 21, "pods": 16}, false)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected := map[string]interface{}{"pods": "10.116.0.0/16", "nodes": "10.117.0.0/21"}
+	if !reflect.DeepEqual(resized.Get("netmasks"), expected) {
+		t.Fatalf("Expected nodes to grow in place, got %v", resized.Get("netmasks"))
+	}
+	if _, diags := resize(map[string]interface{}{"nodes": 21, "pods": 15}, false); !diags.HasError() {
+		t.Fatal("Growing pods into the range of nodes should fail without allow_relocation")
+	}
+	resized, diags = resize(map[string]interface{}{"nodes": 21, "pods": 15}, true)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected = map[string]interface{}{"pods": "10.118.0.0/15", "nodes": "10.117.0.0/21"}
+	if !reflect.DeepEqual(resized.Get("netmasks"), expected) {
+		t.Fatalf("Expected pods to be relocated, got %v", resized.Get("netmasks"))
+	}
+}
+
+func TestNetworkRequestSetReleasesRemovedRanges(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 16})
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	err := connector.Update(ctx, meta.newConnector("10.116.0.0/14"), func(networkConfig *connector.NetworkConfig) error {
+		return networkConfig.SetLease("nodes", "1h")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := updateData(t, resourceNetworkRequestSet(), data, map[string]interface{}{
+		"base_cidr": "10.116.0.0/14",
+		"ranges":    map[string]interface{}{"pods": 16},
+	})
+	if diags := resourceNetworkRequestSetUpdate(ctx, updated, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	networkConfig, err := meta.newConnector("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, leased := networkConfig.Leases["nodes"]; leased || networkConfig.Subnets["nodes"] != "" {
+		t.Fatalf("The removed range should be released together with its lease, got %v and %v", networkConfig.Subnets, networkConfig.Leases)
+	}
+	if events := connector.FilterHistory(networkConfig.History, "nodes", nil); events[len(events)-1].Action != "release" {
+		t.Fatalf("The release should be recorded, got %v", events)
+	}
+}
+
+func TestNetworkRequestSetDropsRangesReleasedOutsideTerraform(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := newNetworkRequestSet(t, map[string]interface{}{"nodes": 22, "pods": 16})
+	if diags := resourceNetworkRequestSetRead(ctx, data, meta); diags.HasError() || data.Id() != "" {
+		t.Fatalf("A missing reservation document should drop the set, got %v", diags)
+	}
+	if diags := resourceNetworkRequestSetCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	release := func(netmaskId string) {
+		err := connector.Update(ctx, meta.newConnector("10.116.0.0/14"), func(networkConfig *connector.NetworkConfig) error {
+			networkConfig.Release(netmaskId)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	release("nodes")
+	if diags := resourceNetworkRequestSetRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if !reflect.DeepEqual(data.Get("ranges"), map[string]interface{}{"pods": 16}) || data.Id() != "memory:10.116.0.0/14:pods" {
+		t.Fatalf("The released range should be dropped, got %v (%s)", data.Get("ranges"), data.Id())
+	}
+	release("pods")
+	if diags := resourceNetworkRequestSetRead(ctx, data, meta); diags.HasError() || data.Id() != "" {
+		t.Fatalf("The set should be dropped once all of its ranges are released, got %v", diags)
+	}
+}
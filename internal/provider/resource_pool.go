@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
+	"strings"
+	"time"
+)
+
+// resourcePool manages the settings of a base cidr range, which apply to all of its reservations.
+func resourcePool() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourcePoolCreate,
+		ReadContext:   resourcePoolRead,
+		UpdateContext: resourcePoolUpdate,
+		DeleteContext: resourcePoolDelete,
+		CustomizeDiff: rejectDocumentChanges("managing pools", "base_cidr", "quarantine_period", "history_limit"),
+
+		Schema: map[string]*schema.Schema{
+			"base_cidr": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"quarantine_period": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+			},
+			"history_limit": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+		},
+		Importer: &schema.ResourceImporter{
+			StateContext: importPoolState,
+		},
+	}
+}
+
+func validateDuration(i interface{}, key string) ([]string, []error) {
+	duration, err := time.ParseDuration(i.(string))
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s is not a valid duration: %s", key, err)}
+	}
+	if duration < 0 {
+		return nil, []error{fmt.Errorf("%s must not be negative!", key)}
+	}
+	return nil, nil
+}
+
+func resourcePoolCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	diags := resourcePoolUpdate(ctx, data, m)
+	if diags.HasError() {
+		return diags
+	}
+	remoteConnector := newRemoteConnector(data, m)
+	data.SetId(fmt.Sprintf("%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange()))
+	return diags
+}
+
+func resourcePoolRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	networkConfig, err := newRemoteConnector(data, m).ReadRemote(ctx)
+	if errors.Is(err, connector.ErrNotExist) {
+		// the settings are gone together with the reservation document
+		data.SetId("")
+		return diags
+	}
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("quarantine_period", networkConfig.QuarantinePeriod); err != nil {
+		return diag.FromErr(err)
+	}
+	if err := data.Set("history_limit", networkConfig.HistoryLimit); err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+func resourcePoolUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	quarantinePeriod := data.Get("quarantine_period").(string)
+	historyLimit := data.Get("history_limit").(int)
+	err := retry(func() error {
+		return update(ctx, m, newRemoteConnector(data, m), func(networkConfig *connector.NetworkConfig) error {
+			networkConfig.QuarantinePeriod = quarantinePeriod
+			networkConfig.HistoryLimit = historyLimit
+			return nil
+		})
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+// resourcePoolDelete resets the settings to their defaults. Ranges already in quarantine stay there until their
+// quarantine period is over.
+func resourcePoolDelete(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	err := retry(func() error {
+		return update(ctx, m, newRemoteConnector(data, m), func(networkConfig *connector.NetworkConfig) error {
+			networkConfig.QuarantinePeriod = ""
+			networkConfig.HistoryLimit = 0
+			return nil
+		})
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return diags
+}
+
+// importPoolState adopts the settings of a base cidr range by an id of the form location:baseCidr.
+func importPoolState(ctx context.Context, data *schema.ResourceData, i interface{}) ([]*schema.ResourceData, error) {
+	baseCidr, err := parsePoolId(data.Id(), i)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := net.ParseCIDR(baseCidr); err != nil {
+		return nil, fmt.Errorf("The base cidr %s of the id %s is invalid: %s", baseCidr, data.Id(), err)
+	}
+	if err := data.Set("base_cidr", baseCidr); err != nil {
+		return nil, err
+	}
+	if diags := resourcePoolRead(ctx, data, i); diags.HasError() {
+		return nil, fmt.Errorf("Failed to read the settings of %s: %v", baseCidr, diags)
+	}
+	return []*schema.ResourceData{data}, nil
+}
+
+// parsePoolId strips the storage location configured for the provider from an id of the form location:baseCidr.
+// Both may contain colons, e.g. https://ipam.example.com:8443:fd00::/48.
+func parsePoolId(id string, m interface{}) (string, error) {
+	// the location does not depend on the base cidr range
+	location := m.(*providerConfig).newConnector("0.0.0.0/0").GetLocation()
+	baseCidr, found := strings.CutPrefix(id, location+":")
+	if !found {
+		return "", fmt.Errorf("The id %s does not refer to %s, which the provider is configured for!", id, location)
+	}
+	return baseCidr, nil
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"testing"
+)
+
+func TestQuarantinedRangeIsNotHandedOutAgain(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	pool := schema.TestResourceDataRaw(t, resourcePool().Schema, map[string]interface{}{
+		"base_cidr":         "10.116.0.0/14",
+		"quarantine_period": "720h",
+	})
+	if diags := resourcePoolCreate(ctx, pool, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if pool.Id() != "memory:10.116.0.0/14" {
+		t.Fatalf("Unexpected id %s", pool.Id())
+	}
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerDelete(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	second := newNetworkRequest(t, "second", 24)
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if second.Get("netmask") != "10.116.1.0/24" {
+		t.Fatalf("The quarantined range must not be handed out, got %s", second.Get("netmask"))
+	}
+	baseCidr := schema.TestResourceDataRaw(t, dataSourceBaseCidr().Schema, map[string]interface{}{"base_cidr": "10.116.0.0/14"})
+	if diags := dataSourceBaseCidrRead(ctx, baseCidr, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if _, contains := baseCidr.Get("quarantined_ranges").(map[string]interface{})["10.116.0.0/24"]; !contains {
+		t.Fatalf("Expected 10.116.0.0/24 in quarantine, got %v", baseCidr.Get("quarantined_ranges"))
+	}
+}
+
+func TestPoolRejectsInvalidQuarantinePeriod(t *testing.T) {
+	if _, errs := validateDuration("a month", "quarantine_period"); len(errs) == 0 {
+		t.Fatal("a month should not be a valid duration")
+	}
+	if _, errs := validateDuration("-1h", "quarantine_period"); len(errs) == 0 {
+		t.Fatal("Negative durations should be rejected")
+	}
+}
+
+func TestPoolIsRemovedFromStateWithoutDocument(t *testing.T) {
+	pool := schema.TestResourceDataRaw(t, resourcePool().Schema, map[string]interface{}{
+		"base_cidr":         "10.116.0.0/14",
+		"quarantine_period": "720h",
+	})
+	pool.SetId("memory:10.116.0.0/14")
+	if diags := resourcePoolRead(context.Background(), pool, newMemoryMeta()); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if pool.Id() != "" {
+		t.Fatalf("A pool without reservation document should be removed from the state, got %s", pool.Id())
+	}
+}
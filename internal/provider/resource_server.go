@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/cidrCalculator"
 	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -18,16 +21,19 @@ func resourceServer() *schema.Resource {
 		ReadContext:   resourceServerRead,
 		UpdateContext: resourceServerUpdate,
 		DeleteContext: resourceServerDelete,
+		CustomizeDiff: customdiff.Sequence(rejectNetworkRequestChanges, previewNetmask),
 
 		Schema: map[string]*schema.Schema{
 			"prefix_length": {
-				Type:     schema.TypeInt,
-				Required: true,
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntBetween(0, 128),
 			},
 			"base_cidr": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
 			},
 			"netmask_id": {
 				Type:     schema.TypeString,
@@ -37,70 +43,271 @@ func resourceServer() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"expected_netmask": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"requested_cidr": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			"allocation_strategy": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice(cidrCalculator.StrategyNames(), false),
+			},
+			"child_pool": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"owner": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"labels": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"lease_duration": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDuration,
+			},
+			"expires_at": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"allow_relocation": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"require_expected_netmask": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"resize_action": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 		Importer: &schema.ResourceImporter{
-			StateContext: schema.ImportStatePassthroughContext,
-			// StateContext: importState,
+			StateContext: importState,
 		},
 	}
 }
 
+// parseImportId splits an import id of the form location:baseCidr:netmaskId, where location has to match the storage
+// location (e.g. the bucket) configured for the provider. Read, Update and Delete take the base cidr range and the
+// netmask id from the state instead, as both the location (e.g. the URL of the http backend) and the base cidr range
+// (IPv6) may contain colons, which only the configured location tells apart.
+func parseImportId(id string, m interface{}) (string, string, error) {
+	withoutLocation, err := parsePoolId(id, m)
+	if err != nil {
+		return "", "", err
+	}
+	separator := strings.LastIndex(withoutLocation, ":")
+	if separator == -1 {
+		return "", "", fmt.Errorf("The id %s does not match the format location:baseCidr:netmaskId!", id)
+	}
+	baseCidr, netmaskId := withoutLocation[:separator], withoutLocation[separator+1:]
+	if _, _, err := net.ParseCIDR(baseCidr); err != nil {
+		return "", "", fmt.Errorf("The base cidr %s of the id %s is invalid: %s", baseCidr, id, err)
+	}
+	return baseCidr, netmaskId, nil
+}
+
+// importState adopts an existing reservation by an id of the form location:baseCidr:netmaskId, see parseImportId.
 func importState(ctx context.Context, data *schema.ResourceData, i interface{}) ([]*schema.ResourceData, error) {
-	idContent := strings.Split(data.Id(), ":")
-	reservatorBucket := idContent[0]
-	baseCidr := idContent[1]
-	netmaskId := idContent[2]
-	gcpConnector := connector.New(reservatorBucket, baseCidr)
-	networkConfig, err := gcpConnector.ReadRemote(ctx)
+	baseCidr, netmaskId, err := parseImportId(data.Id(), i)
 	if err != nil {
 		return nil, err
 	}
+	remoteConnector := i.(*providerConfig).newConnector(baseCidr)
+	if err := readNetworkRequest(ctx, data, remoteConnector, netmaskId); err != nil {
+		return nil, err
+	}
+	return []*schema.ResourceData{data}, nil
+}
+
+// netmaskNotExistError is returned, if a reservation has been released outside of Terraform, e.g. by an expired lease.
+type netmaskNotExistError struct {
+	netmaskId string
+}
+
+func (e netmaskNotExistError) Error() string {
+	return fmt.Sprintf("Netmask with id %s does not exist!", e.netmaskId)
+}
+
+// readNetworkRequest fills data with the stored reservation of netmaskId.
+func readNetworkRequest(ctx context.Context, data *schema.ResourceData, remoteConnector connector.Connector, netmaskId string) error {
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		return err
+	}
 	subnet, contains := networkConfig.Subnets[netmaskId]
 	if !contains {
-		return nil, errors.New(fmt.Sprintf("Netmask with id %s does not exist!", netmaskId))
+		return netmaskNotExistError{netmaskId}
 	}
-	prefixLength := strings.Split(subnet, "/")[1]
-	data.Set("base_cidr", baseCidr)
-	data.Set("netmask_id", netmaskId)
-	data.Set("prefix_length", prefixLength)
-	return []*schema.ResourceData{data}, nil
+	prefixLength, err := strconv.Atoi(strings.Split(subnet, "/")[1])
+	if err != nil {
+		return err
+	}
+	data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), netmaskId))
+	if err := data.Set("base_cidr", remoteConnector.GetBaseCidrRange()); err != nil {
+		return err
+	}
+	if err := data.Set("netmask_id", netmaskId); err != nil {
+		return err
+	}
+	if err := data.Set("prefix_length", prefixLength); err != nil {
+		return err
+	}
+	if err := data.Set("child_pool", networkConfig.ChildPools[netmaskId] != ""); err != nil {
+		return err
+	}
+	if err := setReservationMetadata(data, networkConfig.Metadata[netmaskId]); err != nil {
+		return err
+	}
+	lease := networkConfig.Leases[netmaskId]
+	if err := data.Set("lease_duration", lease.Duration); err != nil {
+		return err
+	}
+	if err := data.Set("expires_at", lease.ExpiresAt); err != nil {
+		return err
+	}
+	return setNetmask(data, subnet)
+}
+
+// setNetmask stores the reserved netmask, which the plan of the next change expects, unless it relocates it.
+func setNetmask(data *schema.ResourceData, netmask string) error {
+	if err := data.Set("netmask", netmask); err != nil {
+		return err
+	}
+	return data.Set("expected_netmask", netmask)
+}
+
+// reservationMetadata returns the description, owner and labels configured for a reservation.
+func reservationMetadata(data *schema.ResourceData) connector.ReservationMetadata {
+	metadata := connector.ReservationMetadata{
+		Description: data.Get("description").(string),
+		Owner:       data.Get("owner").(string),
+	}
+	if labels := data.Get("labels").(map[string]interface{}); len(labels) > 0 {
+		metadata.Labels = make(map[string]string, len(labels))
+		for key, value := range labels {
+			metadata.Labels[key] = value.(string)
+		}
+	}
+	return metadata
 }
 
-func readRemote(ctx context.Context, data *schema.ResourceData, m interface{}) (*connector.NetworkConfig, *connector.GcpConnector, error) {
-	cidrProviderBucket := m.(string)
-	gcpConnector := connector.New(cidrProviderBucket, data.Get("base_cidr").(string))
-	networkConfig, err := gcpConnector.ReadRemote(ctx)
+func setReservationMetadata(data *schema.ResourceData, metadata connector.ReservationMetadata) error {
+	if err := data.Set("description", metadata.Description); err != nil {
+		return err
+	}
+	if err := data.Set("owner", metadata.Owner); err != nil {
+		return err
+	}
+	return data.Set("labels", metadata.Labels)
+}
+
+// resizeInPlace and resizeRelocate tell how a reservation is resized, see resizeNetmask.
+const (
+	resizeInPlace  = "in_place"
+	resizeRelocate = "relocate"
+)
+
+// resizeNetmask returns the netmask of the reservation netmaskId resized to prefixLength together with the resize
+// action. The reservation is resized in place if possible; otherwise it is relocated to the next free range, but only
+// if allowRelocation is set.
+func resizeNetmask(networkConfig *connector.NetworkConfig, netmaskId string, prefixLength int, baseCidrRange string, strategy cidrCalculator.Strategy, allowRelocation bool) (string, string, error) {
+	netmask, err := networkConfig.ResizeInPlace(netmaskId, prefixLength, baseCidrRange)
+	if err == nil {
+		return netmask, resizeInPlace, nil
+	}
+	if !allowRelocation {
+		return "", "", fmt.Errorf("%s Set allow_relocation to move the reservation %s to another range instead!", err, netmaskId)
+	}
+	occupied := networkConfig.OccupiedSubnets()
+	netmask, err = cidrCalculator.NextNetmask(&occupied, prefixLength, baseCidrRange, strategy)
+	return netmask, resizeRelocate, err
+}
+
+// verifyRequestedPrefixLength checks, that prefix_length matches the one of requested_cidr, if it is set.
+func verifyRequestedPrefixLength(requestedCidr string, prefixLength int) error {
+	if requestedCidr == "" {
+		return nil
+	}
+	_, requestedIPNet, err := net.ParseCIDR(requestedCidr)
 	if err != nil {
-		if err.Error() == "storage: object doesn't exist" {
-			err = nil
-			networkConfig = &connector.NetworkConfig{Subnets: make(map[string]string)}
-		} else {
-			return nil, nil, err
+		return err
+	}
+	if requestedPrefixLength, _ := requestedIPNet.Mask.Size(); requestedPrefixLength != prefixLength {
+		return fmt.Errorf("prefix_length %d does not match the prefix length of requested_cidr %s!", prefixLength, requestedCidr)
+	}
+	return nil
+}
+
+func newRemoteConnector(data *schema.ResourceData, m interface{}) connector.Connector {
+	return m.(*providerConfig).newConnector(data.Get("base_cidr").(string))
+}
+
+// update runs connector.Update on behalf of the caller configured for the provider.
+func update(ctx context.Context, m interface{}, remoteConnector connector.Connector, modify func(networkConfig *connector.NetworkConfig) error) error {
+	return connector.Update(connector.WithCaller(ctx, m.(*providerConfig).caller), remoteConnector, modify)
+}
+
+// rejectOnReservationService fails the plan of a change, which has to write the reservation document, if the http
+// backend is configured, so it does not only fail at apply. Its reservation service only reserves and releases network
+// requests, see connector.HttpConnector.
+func rejectOnReservationService(diff *schema.ResourceDiff, m interface{}, change string) error {
+	if _, ok := m.(*providerConfig).newConnector(diff.Get("base_cidr").(string)).(connector.Reserver); ok {
+		return fmt.Errorf("The http backend only reserves and releases network requests, %s is not supported by its reservation service!", change)
+	}
+	return nil
+}
+
+// rejectDocumentChanges returns a CustomizeDiffFunc, which rejects creating the resource or changing any of keys on
+// the http backend, see rejectOnReservationService.
+func rejectDocumentChanges(what string, keys ...string) schema.CustomizeDiffFunc {
+	return func(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+		if diff.Id() != "" && !diff.HasChanges(keys...) {
+			return nil
 		}
+		return rejectOnReservationService(diff, m, what)
 	}
-	//netmaskId := data.Get("netmask_id").(string)
-	//data.SetId("")
-	//if netmask, contains := networkConfig.Subnets[netmaskId]; contains {
-	//	data.SetId(netmaskId)
-	//	err = data.Set("netmask", netmask)
-	//	if err != nil {
-	//		return nil, nil, err
-	//	}
-	//}
-	return networkConfig, &gcpConnector, nil
 }
 
-func retry(toRetry func() error) error {
-	n := 0
-	var err error
-	for n < 4 {
-		err = toRetry()
-		if err == nil {
-			break
+// rejectNetworkRequestChanges rejects child pools and changes of existing network requests on the http backend, see
+// rejectOnReservationService. They can only be replaced there.
+func rejectNetworkRequestChanges(ctx context.Context, diff *schema.ResourceDiff, m interface{}) error {
+	if diff.Id() == "" {
+		if diff.Get("child_pool").(bool) {
+			return rejectOnReservationService(diff, m, "reserving child pools")
+		}
+		return nil
+	}
+	for _, key := range []string{"prefix_length", "netmask_id", "allocation_strategy", "child_pool", "description", "owner", "labels", "lease_duration", "allow_relocation", "require_expected_netmask"} {
+		if diff.HasChange(key) {
+			return rejectOnReservationService(diff, m, fmt.Sprintf("changing %s of an existing network request", key))
 		}
-		n++
 	}
-	return err
+	return nil
+}
+
+// retry runs toRetry again on concurrent modifications and on failures to reach the backend, see connector.Retryable.
+func retry(toRetry func() error) error {
+	return connector.Retry(toRetry, connector.Retryable)
 }
 
 func resourceServerCreate(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -109,77 +316,119 @@ func resourceServerCreate(ctx context.Context, data *schema.ResourceData, m inte
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if data.Get("child_pool").(bool) {
+		err = promoteChildPool(ctx, m, data.Get("base_cidr").(string), data.Get("netmask_id").(string), data.Get("netmask").(string))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
 	return diags
 }
 
 func innerResourceServerCreate(ctx context.Context, data *schema.ResourceData, m interface{}) func() error {
 	return func() error {
-		networkConfig, gcpConnector, err := readRemote(ctx, data, m)
-		if err != nil {
-			return err
-		}
-		if data.Get("id") != nil {
-			return nil
-		}
+		remoteConnector := newRemoteConnector(data, m)
 		netmaskId := data.Get("netmask_id").(string)
-		if _, contains := networkConfig.Subnets[netmaskId]; contains {
-			return fmt.Errorf("The netmaskId %s already exists, but does not belong to your Terraform state!!!", netmaskId)
-		}
-		prefixLength := int8(data.Get("prefix_length").(int))
-		if err != nil {
+		prefixLength := data.Get("prefix_length").(int)
+		strategy := cidrCalculator.Strategy(data.Get("allocation_strategy").(string))
+		requestedCidr := data.Get("requested_cidr").(string)
+		if err := verifyRequestedPrefixLength(requestedCidr, prefixLength); err != nil {
 			return err
 		}
-		newCidrCalculator, err := cidrCalculator.New(&networkConfig.Subnets, prefixLength, gcpConnector.BaseCidrRange)
-		if err != nil {
-			return err
+		childPool := data.Get("child_pool").(bool)
+		metadata := reservationMetadata(data)
+		leaseDuration := data.Get("lease_duration").(string)
+		planned := plannedNetmask(data)
+		request := connector.ReservationRequest{
+			NetmaskId:     netmaskId,
+			PrefixLength:  prefixLength,
+			RequestedCidr: requestedCidr,
+			Strategy:      strategy,
+			Metadata:      metadata,
+			LeaseDuration: leaseDuration,
+			ChildPool:     childPool,
 		}
-		nextNetmask, err := newCidrCalculator.GetNextNetmask()
-		if err != nil {
-			return err
+		var nextNetmask, expiresAt string
+		var err error
+		if reserver, ok := remoteConnector.(connector.Reserver); ok {
+			nextNetmask, expiresAt, err = reserveFromService(connector.WithCaller(ctx, m.(*providerConfig).caller), reserver, request, planned)
+		} else {
+			err = update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+				var err error
+				nextNetmask, err = networkConfig.Reserve(ctx, m.(*providerConfig).newConnector, remoteConnector.GetBaseCidrRange(), request)
+				if errors.Is(err, connector.ErrNetmaskIdExists) {
+					return fmt.Errorf("The netmaskId %s already exists, but does not belong to your Terraform state!!!", netmaskId)
+				}
+				if err != nil {
+					return err
+				}
+				if err := verifyPlannedNetmask(planned, nextNetmask); err != nil {
+					return err
+				}
+				expiresAt = networkConfig.Leases[netmaskId].ExpiresAt
+				return nil
+			})
 		}
-		networkConfig.Subnets[netmaskId] = nextNetmask
-		err = gcpConnector.WriteRemote(networkConfig, ctx)
 		if err != nil {
 			return err
 		}
-		data.SetId(fmt.Sprintf("%s:%s:%s", gcpConnector.BucketName, gcpConnector.BaseCidrRange, netmaskId))
-		err = data.Set("netmask", nextNetmask)
+		data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), netmaskId))
+		err = setNetmask(data, nextNetmask)
 		if err != nil {
 			return err
 		}
-		return nil
+		return data.Set("expires_at", expiresAt)
 	}
 }
 
+// reserveFromService reserves through a reservation service, which decides about the range, and returns it together
+// with the end of its lease. A range other than the planned one is released again.
+func reserveFromService(ctx context.Context, reserver connector.Reserver, request connector.ReservationRequest, planned string) (string, string, error) {
+	reservation, err := reserver.Reserve(ctx, request)
+	if err != nil {
+		return "", "", err
+	}
+	if err := verifyPlannedNetmask(planned, reservation.Cidr); err != nil {
+		return "", "", errors.Join(err, reserver.Release(ctx, request.NetmaskId))
+	}
+	return reservation.Cidr, reservation.ExpiresAt, nil
+}
+
 func resourceServerRead(ctx context.Context, data *schema.ResourceData, m interface{}) diag.Diagnostics {
 	var diags diag.Diagnostics
-	idContent := strings.Split(data.Id(), ":")
-	reservatorBucket := idContent[0]
-	baseCidr := idContent[1]
-	netmaskId := idContent[2]
-	gcpConnector := connector.New(reservatorBucket, baseCidr)
-	networkConfig, err := gcpConnector.ReadRemote(ctx)
-	if err != nil {
-		return diag.FromErr(err)
+	netmaskId := data.Get("netmask_id").(string)
+	remoteConnector := newRemoteConnector(data, m)
+	var err error
+	// reservation services renew leases themselves, if at all
+	_, isReserver := remoteConnector.(connector.Reserver)
+	if leaseDuration := data.Get("lease_duration").(string); leaseDuration != "" && !isReserver {
+		reclaimed := false
+		err = retry(func() error {
+			return update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+				_, contains := networkConfig.Subnets[netmaskId]
+				reclaimed = !contains
+				if reclaimed {
+					return nil
+				}
+				return networkConfig.SetLease(netmaskId, leaseDuration)
+			})
+		})
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if reclaimed {
+			data.SetId("")
+			return diags
+		}
 	}
-	subnet, contains := networkConfig.Subnets[netmaskId]
-	if !contains {
-		return diag.FromErr(errors.New(fmt.Sprintf("Netmask with id %s does not exist!", netmaskId)))
+	err = readNetworkRequest(ctx, data, remoteConnector, netmaskId)
+	if errors.As(err, &netmaskNotExistError{}) || errors.Is(err, connector.ErrNotExist) {
+		data.SetId("")
+		return diags
 	}
-	prefixLength := strings.Split(subnet, "/")[1]
-	data.Set("base_cidr", baseCidr)
-	data.Set("netmask_id", netmaskId)
-	data.Set("prefix_length", prefixLength)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	if data.Get("id") != nil {
-		return nil
-	}
-	if _, contains := networkConfig.Subnets[netmaskId]; !contains {
-		return diag.FromErr(fmt.Errorf("The netmask for the netmaskId %s does not exist!!!", netmaskId))
-	}
-	data.Set("netmask", networkConfig.Subnets[netmaskId])
 	return diags
 }
 
@@ -196,48 +445,99 @@ func resourceServerUpdate(ctx context.Context, data *schema.ResourceData, m inte
 // TODO: Update of netmask_id should not enforce recreate.
 func innerResourceServerUpdate(ctx context.Context, data *schema.ResourceData, m interface{}) func() error {
 	return func() error {
-		networkConfig, gcpConnector, err := readRemote(ctx, data, m)
-		if err != nil {
-			return err
-		}
-		id := data.Id()
-		valuesFromId := strings.Split(id, ":")
+		remoteConnector := newRemoteConnector(data, m)
+		// the reservation is looked up by the state, as the configuration may rename it; base_cidr forces a new one
+		previousNetmaskId, _ := data.GetChange("netmask_id")
+		currentNetmaskId := previousNetmaskId.(string)
 		netmaskId := data.Get("netmask_id").(string)
-		netmaskIdFromId := valuesFromId[2]
-		currentSubnet := networkConfig.Subnets[netmaskIdFromId]
-		if netmaskIdFromId != netmaskId {
-			delete(networkConfig.Subnets, netmaskIdFromId)
-			if _, contains := networkConfig.Subnets[netmaskId]; contains {
-				return fmt.Errorf("The netmaskId %s already exists, but does not belong to your Terraform state!!!", netmaskId)
-			}
-			networkConfig.Subnets[netmaskId] = currentSubnet
+		prefixLength := data.Get("prefix_length").(int)
+		strategy := cidrCalculator.Strategy(data.Get("allocation_strategy").(string))
+		if err := verifyRequestedPrefixLength(data.Get("requested_cidr").(string), prefixLength); err != nil {
+			return err
 		}
-		currentPrefixLength, err := strconv.ParseInt(strings.Split(currentSubnet, "/")[1], 10, 8)
+		childPool := data.Get("child_pool").(bool)
+		allowRelocation := data.Get("allow_relocation").(bool)
+		currentChildPool, err := childPoolOf(ctx, remoteConnector, currentNetmaskId)
 		if err != nil {
 			return err
 		}
-		prefixLength := int8(data.Get("prefix_length").(int))
-		baseCidrRangeFromId := valuesFromId[1]
-		if (baseCidrRangeFromId != gcpConnector.BaseCidrRange) || (int8(currentPrefixLength) != prefixLength) {
-			newCidrCalculator, err := cidrCalculator.New(&networkConfig.Subnets, int8(prefixLength), gcpConnector.BaseCidrRange)
-			if err != nil {
+		if currentChildPool != "" && !childPool {
+			if err := releaseChildPool(ctx, m, remoteConnector.GetBaseCidrRange(), currentNetmaskId, currentChildPool); err != nil {
 				return err
 			}
-			nextNetmask, err := newCidrCalculator.GetNextNetmask()
-			if err != nil {
+		}
+		metadata := reservationMetadata(data)
+		leaseDuration := data.Get("lease_duration").(string)
+		planned := plannedNetmask(data)
+		var nextNetmask, expiresAt, resizeAction string
+		err = update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+			if err := networkConfig.VerifyChildPool(ctx, m.(*providerConfig).newConnector, remoteConnector.GetBaseCidrRange()); err != nil {
 				return err
 			}
-			networkConfig.Subnets[netmaskId] = nextNetmask
-			err = data.Set("netmask", nextNetmask)
+			currentSubnet, contains := networkConfig.Subnets[currentNetmaskId]
+			if !contains {
+				return netmaskNotExistError{currentNetmaskId}
+			}
+			if currentNetmaskId != netmaskId {
+				delete(networkConfig.Subnets, currentNetmaskId)
+				if _, contains := networkConfig.Subnets[netmaskId]; contains {
+					return fmt.Errorf("The netmaskId %s already exists, but does not belong to your Terraform state!!!", netmaskId)
+				}
+				networkConfig.Subnets[netmaskId] = currentSubnet
+			}
+			currentPrefixLength, err := strconv.Atoi(strings.Split(currentSubnet, "/")[1])
 			if err != nil {
 				return err
 			}
+			nextNetmask = currentSubnet
+			if networkConfig.ChildPools[currentNetmaskId] != "" && childPool && (currentNetmaskId != netmaskId || currentPrefixLength != prefixLength) {
+				return fmt.Errorf("The netmaskId %s has been promoted into a child pool, which can neither be renamed nor resized!", currentNetmaskId)
+			}
+			if currentPrefixLength != prefixLength {
+				nextNetmask, resizeAction, err = resizeNetmask(networkConfig, netmaskId, prefixLength, remoteConnector.GetBaseCidrRange(), strategy, allowRelocation)
+				if err != nil {
+					return err
+				}
+				if err := verifyPlannedNetmask(planned, nextNetmask); err != nil {
+					return err
+				}
+				networkConfig.Subnets[netmaskId] = nextNetmask
+			}
+			delete(networkConfig.Metadata, currentNetmaskId)
+			networkConfig.SetMetadata(netmaskId, metadata)
+			delete(networkConfig.Leases, currentNetmaskId)
+			if err := networkConfig.SetLease(netmaskId, leaseDuration); err != nil {
+				return err
+			}
+			expiresAt = networkConfig.Leases[netmaskId].ExpiresAt
+			delete(networkConfig.ChildPools, currentNetmaskId)
+			if childPool {
+				if networkConfig.ChildPools == nil {
+					networkConfig.ChildPools = make(map[string]string)
+				}
+				networkConfig.ChildPools[netmaskId] = nextNetmask
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-		err = gcpConnector.WriteRemote(networkConfig, ctx)
+		err = setNetmask(data, nextNetmask)
 		if err != nil {
 			return err
 		}
-		data.SetId(fmt.Sprintf("%s:%s:%s", gcpConnector.BucketName, gcpConnector.BaseCidrRange, netmaskId))
+		if err := data.Set("expires_at", expiresAt); err != nil {
+			return err
+		}
+		if resizeAction != "" {
+			if err := data.Set("resize_action", resizeAction); err != nil {
+				return err
+			}
+		}
+		data.SetId(fmt.Sprintf("%s:%s:%s", remoteConnector.GetLocation(), remoteConnector.GetBaseCidrRange(), netmaskId))
+		if childPool && currentChildPool == "" {
+			return promoteChildPool(ctx, m, remoteConnector.GetBaseCidrRange(), netmaskId, nextNetmask)
+		}
 		return nil
 	}
 }
@@ -253,16 +553,23 @@ func resourceServerDelete(ctx context.Context, data *schema.ResourceData, m inte
 
 func innerResourceServerDelete(ctx context.Context, data *schema.ResourceData, m interface{}) func() error {
 	return func() error {
-		networkConfig, gcpConnector, err := readRemote(ctx, data, m)
-		if err != nil {
-			return err
-		}
 		netmaskId := data.Get("netmask_id").(string)
-		delete(networkConfig.Subnets, netmaskId)
-		err = gcpConnector.WriteRemote(networkConfig, ctx)
+		remoteConnector := newRemoteConnector(data, m)
+		if reserver, ok := remoteConnector.(connector.Reserver); ok {
+			return reserver.Release(connector.WithCaller(ctx, m.(*providerConfig).caller), netmaskId)
+		}
+		childPool, err := childPoolOf(ctx, remoteConnector, netmaskId)
 		if err != nil {
 			return err
 		}
-		return nil
+		if childPool != "" {
+			if err := releaseChildPool(ctx, m, remoteConnector.GetBaseCidrRange(), netmaskId, childPool); err != nil {
+				return err
+			}
+		}
+		return update(ctx, m, remoteConnector, func(networkConfig *connector.NetworkConfig) error {
+			networkConfig.Release(netmaskId)
+			return nil
+		})
 	}
 }
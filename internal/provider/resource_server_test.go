@@ -0,0 +1,503 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/sbehl27-org/terraform-provider-cidr-reservator/internal/provider/connector"
+	"strings"
+	"testing"
+)
+
+// memoryConnector keeps the reservation documents in memory and uses a version counter as version token.
+type memoryConnector struct {
+	documents map[string][]byte
+	versions  map[string]int64
+	baseCidr  string
+	version   int64
+}
+
+func newMemoryMeta() *providerConfig {
+	documents := make(map[string][]byte)
+	versions := make(map[string]int64)
+	return &providerConfig{newConnector: func(baseCidr string) connector.Connector {
+		return &memoryConnector{documents, versions, baseCidr, -1}
+	}}
+}
+
+func (m *memoryConnector) ReadRemote(ctx context.Context) (*connector.NetworkConfig, error) {
+	networkConfig := connector.NetworkConfig{}
+	document, contains := m.documents[m.baseCidr]
+	if !contains {
+		return &networkConfig, connector.ErrNotExist
+	}
+	m.version = m.versions[m.baseCidr]
+	return &networkConfig, json.Unmarshal(document, &networkConfig)
+}
+
+func (m *memoryConnector) WriteRemote(networkConfig *connector.NetworkConfig, ctx context.Context) error {
+	current, contains := m.versions[m.baseCidr]
+	if (m.version == -1 && contains) || (m.version != -1 && current != m.version) {
+		return connector.ErrConflict
+	}
+	marshalled, err := json.Marshal(networkConfig)
+	if err != nil {
+		return err
+	}
+	m.documents[m.baseCidr] = marshalled
+	m.versions[m.baseCidr] = current + 1
+	m.version = current + 1
+	return nil
+}
+
+func (m *memoryConnector) GetBaseCidrRange() string {
+	return m.baseCidr
+}
+
+func (m *memoryConnector) GetLocation() string {
+	return "memory"
+}
+
+func newNetworkRequest(t *testing.T, netmaskId string, prefixLength int) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    netmaskId,
+		"prefix_length": prefixLength,
+	})
+}
+
+// updateData returns the data, with which Terraform updates current to config. Unlike TestResourceDataRaw, it
+// carries the state of current, which Update reads the reservation by. The plan is not customized, so Update sees
+// changes the preview would already reject.
+func updateData(t *testing.T, resource *schema.Resource, current *schema.ResourceData, config map[string]interface{}) *schema.ResourceData {
+	diff, err := schema.InternalMap(resource.Schema).Diff(context.Background(), current.State(), terraform.NewResourceConfigRaw(config), nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := schema.InternalMap(resource.Schema).Data(current.State(), diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCreateReservesNextNetmask(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	second := newNetworkRequest(t, "second", 24)
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if first.Get("netmask") != "10.116.0.0/24" || second.Get("netmask") != "10.116.1.0/24" {
+		t.Fatalf("Unexpected netmasks %s and %s", first.Get("netmask"), second.Get("netmask"))
+	}
+	if second.Id() != "memory:10.116.0.0/14:second" {
+		t.Fatalf("Unexpected id %s", second.Id())
+	}
+}
+
+func TestCreateFailsForExistingNetmaskId(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 26), meta); !diags.HasError() {
+		t.Fatal("Creating a netmask_id which already exists should fail!")
+	}
+}
+
+func TestDeleteReleasesNetmask(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if diags := resourceServerDelete(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	second := newNetworkRequest(t, "second", 24)
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if second.Get("netmask") != "10.116.0.0/24" {
+		t.Fatalf("Released netmask should be reused, got %s", second.Get("netmask"))
+	}
+}
+
+func TestCreateAndReadIPv6(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":     "fd00:1::/48",
+		"netmask_id":    "first",
+		"prefix_length": 64,
+	})
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Id() != "memory:fd00:1::/48:first" || data.Get("netmask") != "fd00:1::/64" {
+		t.Fatalf("Unexpected id %s or netmask %s", data.Id(), data.Get("netmask"))
+	}
+	if diags := resourceServerRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("base_cidr") != "fd00:1::/48" || data.Get("netmask_id") != "first" {
+		t.Fatalf("Unexpected base_cidr %s or netmask_id %s", data.Get("base_cidr"), data.Get("netmask_id"))
+	}
+}
+
+// locatedConnector overrides the location of a connector, e.g. with the URL of the http backend.
+type locatedConnector struct {
+	connector.Connector
+	location string
+}
+
+func (l locatedConnector) GetLocation() string {
+	return l.location
+}
+
+func TestParseImportIdWithColonsInLocation(t *testing.T) {
+	memory := newMemoryMeta()
+	meta := &providerConfig{newConnector: func(baseCidr string) connector.Connector {
+		return locatedConnector{memory.newConnector(baseCidr), "https://ipam.example.com:8443"}
+	}}
+	baseCidr, netmaskId, err := parseImportId("https://ipam.example.com:8443:fd00:1::/48:first", meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseCidr != "fd00:1::/48" || netmaskId != "first" {
+		t.Fatalf("Unexpected base cidr %s or netmask id %s", baseCidr, netmaskId)
+	}
+	for _, id := range []string{"", "https://ipam.example.com:8443", "https://ipam.example.com:8443:first", "https://ipam.example.com:9443:10.116.0.0/14:first"} {
+		if _, _, err := parseImportId(id, meta); err == nil {
+			t.Fatalf("id %s should be rejected", id)
+		}
+	}
+}
+
+func TestImportFillsReservation(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := resourceServer().Data(nil)
+	data.SetId("memory:10.116.0.0/14:first")
+	imported, err := importState(ctx, data, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported[0].Get("base_cidr") != "10.116.0.0/14" || imported[0].Get("netmask_id") != "first" || imported[0].Get("prefix_length") != 24 || imported[0].Get("netmask") != "10.116.0.0/24" {
+		t.Fatalf("Unexpected imported state %v", imported[0].State())
+	}
+}
+
+func TestImportRejectsInvalidIds(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	for _, id := range []string{"first", "memory:10.116.0.0:first", "other-bucket:10.116.0.0/14:first", "memory:10.116.0.0/14:unknown"} {
+		data := resourceServer().Data(nil)
+		data.SetId(id)
+		if _, err := importState(ctx, data, meta); err == nil {
+			t.Fatalf("Importing %s should fail!", id)
+		}
+	}
+}
+
+func TestCreateWithAllocationStrategy(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	for _, netmaskId := range []string{"first", "second"} {
+		if diags := resourceServerCreate(ctx, newNetworkRequest(t, netmaskId, 24), meta); diags.HasError() {
+			t.Fatal(diags)
+		}
+	}
+	if diags := resourceServerDelete(ctx, newNetworkRequest(t, "first", 24), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	data := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":           "10.116.0.0/14",
+		"netmask_id":          "third",
+		"prefix_length":       24,
+		"allocation_strategy": "append",
+	})
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("netmask") != "10.116.2.0/24" {
+		t.Fatalf("The append strategy should not backfill, got %s", data.Get("netmask"))
+	}
+}
+
+func TestCreateWithRequestedCidr(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	legacy := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":      "10.116.0.0/14",
+		"netmask_id":     "legacy",
+		"prefix_length":  24,
+		"requested_cidr": "10.116.0.0/24",
+	})
+	if diags := resourceServerCreate(ctx, legacy, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if legacy.Get("netmask") != "10.116.0.0/24" {
+		t.Fatalf("Unexpected netmask %s", legacy.Get("netmask"))
+	}
+	next := newNetworkRequest(t, "next", 24)
+	if diags := resourceServerCreate(ctx, next, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if next.Get("netmask") != "10.116.1.0/24" {
+		t.Fatalf("The requested cidr must not be handed out again, got %s", next.Get("netmask"))
+	}
+	conflicting := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":      "10.116.0.0/14",
+		"netmask_id":     "conflicting",
+		"prefix_length":  23,
+		"requested_cidr": "10.116.0.0/23",
+	})
+	if diags := resourceServerCreate(ctx, conflicting, meta); !diags.HasError() {
+		t.Fatal("Requesting an already reserved cidr should fail!")
+	}
+}
+
+func TestMetadataIsStoredWithReservation(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "first",
+		"prefix_length": 24,
+		"owner":         "team-a",
+		"description":   "Subnet of team A",
+		"labels":        map[string]interface{}{"environment": "test"},
+	})
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	read := schema.TestResourceDataRaw(t, dataSourceNetworkRequest().Schema, map[string]interface{}{
+		"base_cidr":  "10.116.0.0/14",
+		"netmask_id": "first",
+	})
+	if diags := dataSourceNetworkRequestRead(ctx, read, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if read.Get("owner") != "team-a" || read.Get("description") != "Subnet of team A" || read.Get("labels").(map[string]interface{})["environment"] != "test" {
+		t.Fatalf("Unexpected metadata %s, %s, %v", read.Get("owner"), read.Get("description"), read.Get("labels"))
+	}
+	updated := updateData(t, resourceServer(), data, map[string]interface{}{
+		"base_cidr":     "10.116.0.0/14",
+		"netmask_id":    "first",
+		"prefix_length": 24,
+	})
+	if diags := resourceServerUpdate(ctx, updated, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	networkConfig, err := meta.newConnector("10.116.0.0/14").ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, contains := networkConfig.Metadata["first"]; contains || networkConfig.Subnets["first"] != "10.116.0.0/24" {
+		t.Fatalf("Removing all metadata should keep the reservation only, got %v and %v", networkConfig.Metadata, networkConfig.Subnets)
+	}
+}
+
+func TestReadRenewsLeaseUntilReclaimed(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	data := schema.TestResourceDataRaw(t, resourceServer().Schema, map[string]interface{}{
+		"base_cidr":      "10.116.0.0/14",
+		"netmask_id":     "preview",
+		"prefix_length":  24,
+		"lease_duration": "1h",
+	})
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Get("expires_at") == "" {
+		t.Fatal("expires_at should be set for leases")
+	}
+	if diags := resourceServerRead(ctx, data, meta); diags.HasError() || data.Id() == "" {
+		t.Fatalf("Reading should renew the lease, got %v", diags)
+	}
+	remoteConnector := meta.newConnector("10.116.0.0/14")
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig.Leases["preview"] = connector.Lease{Duration: "1h", ExpiresAt: "2000-01-01T00:00:00Z"}
+	if err := remoteConnector.WriteRemote(networkConfig, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceServerRead(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Id() != "" {
+		t.Fatal("An expired lease should be reclaimed and removed from the state")
+	}
+}
+
+func TestUpdateResizesInPlaceUnlessRelocationIsAllowed(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	if diags := resourceServerCreate(ctx, newNetworkRequest(t, "first", 25), meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	second := newNetworkRequest(t, "second", 26)
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resize := func(prefixLength int, allowRelocation bool) (*schema.ResourceData, diag.Diagnostics) {
+		resized := updateData(t, resourceServer(), second, map[string]interface{}{
+			"base_cidr":        "10.116.0.0/14",
+			"netmask_id":       "second",
+			"prefix_length":    prefixLength,
+			"allow_relocation": allowRelocation,
+		})
+		diags := resourceServerUpdate(ctx, resized, meta)
+		if !diags.HasError() {
+			second = resized
+		}
+		return resized, diags
+	}
+	resized, diags := resize(25, false)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if resized.Get("netmask") != "10.116.0.128/25" || resized.Get("resize_action") != "in_place" {
+		t.Fatalf("Expected 10.116.0.128/26 to grow in place to 10.116.0.128/25, got %s (%s)", resized.Get("netmask"), resized.Get("resize_action"))
+	}
+	if _, diags := resize(24, false); !diags.HasError() {
+		t.Fatal("Growing into the range of first should fail without allow_relocation")
+	}
+	resized, diags = resize(24, true)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if resized.Get("netmask") != "10.116.1.0/24" || resized.Get("resize_action") != "relocate" {
+		t.Fatalf("Expected a relocation to 10.116.1.0/24, got %s (%s)", resized.Get("netmask"), resized.Get("resize_action"))
+	}
+	resized, diags = resize(26, false)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	if resized.Get("netmask") != "10.116.1.0/26" || resized.Get("resize_action") != "in_place" {
+		t.Fatalf("Expected 10.116.1.0/24 to shrink in place to 10.116.1.0/26, got %s (%s)", resized.Get("netmask"), resized.Get("resize_action"))
+	}
+}
+
+func TestResizeInPlaceWithQuarantinePeriod(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	pool := schema.TestResourceDataRaw(t, resourcePool().Schema, map[string]interface{}{
+		"base_cidr":         "10.116.0.0/14",
+		"quarantine_period": "720h",
+	})
+	if diags := resourcePoolCreate(ctx, pool, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	resize := func(prefixLength int) {
+		resized := updateData(t, resourceServer(), first, map[string]interface{}{
+			"base_cidr":     "10.116.0.0/14",
+			"netmask_id":    "first",
+			"prefix_length": prefixLength,
+		})
+		if diags := resourceServerUpdate(ctx, resized, meta); diags.HasError() {
+			t.Fatal(diags)
+		}
+		first = resized
+	}
+	quarantined := func() map[string]interface{} {
+		baseCidr := schema.TestResourceDataRaw(t, dataSourceBaseCidr().Schema, map[string]interface{}{"base_cidr": "10.116.0.0/14"})
+		if diags := dataSourceBaseCidrRead(ctx, baseCidr, meta); diags.HasError() {
+			t.Fatal(diags)
+		}
+		return baseCidr.Get("quarantined_ranges").(map[string]interface{})
+	}
+	resize(25)
+	if ranges := quarantined(); len(ranges) != 1 || ranges["10.116.0.128/25"] == nil {
+		t.Fatalf("Only the freed 10.116.0.128/25 should be in quarantine, got %v", ranges)
+	}
+	second := newNetworkRequest(t, "second", 25)
+	if diags := resourceServerCreate(ctx, second, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if second.Get("netmask") != "10.116.1.0/25" {
+		t.Fatalf("The freed range must not be handed out during its quarantine, got %s", second.Get("netmask"))
+	}
+	resize(24)
+	if first.Get("netmask") != "10.116.0.0/24" || first.Get("resize_action") != "in_place" {
+		t.Fatalf("Expected first to grow back in place to 10.116.0.0/24, got %s (%s)", first.Get("netmask"), first.Get("resize_action"))
+	}
+	if ranges := quarantined(); len(ranges) != 0 {
+		t.Fatalf("Growing in place should not quarantine anything, got %v", ranges)
+	}
+}
+
+func TestUpdateFailsForReclaimedLease(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	config := map[string]interface{}{
+		"base_cidr":      "10.116.0.0/14",
+		"netmask_id":     "preview",
+		"prefix_length":  24,
+		"lease_duration": "1h",
+	}
+	data := schema.TestResourceDataRaw(t, resourceServer().Schema, config)
+	if diags := resourceServerCreate(ctx, data, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	remoteConnector := meta.newConnector("10.116.0.0/14")
+	networkConfig, err := remoteConnector.ReadRemote(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkConfig.Leases["preview"] = connector.Lease{Duration: "1h", ExpiresAt: "2000-01-01T00:00:00Z"}
+	if err := remoteConnector.WriteRemote(networkConfig, ctx); err != nil {
+		t.Fatal(err)
+	}
+	config["description"] = "changed"
+	updated := updateData(t, resourceServer(), data, config)
+	diags := resourceServerUpdate(ctx, updated, meta)
+	if !diags.HasError() || !strings.Contains(diags[0].Summary, "does not exist") {
+		t.Fatalf("Updating a reclaimed reservation should fail, got %v", diags)
+	}
+}
+
+func TestReadRemovesReservationReleasedOutsideTerraform(t *testing.T) {
+	ctx := context.Background()
+	meta := newMemoryMeta()
+	first := newNetworkRequest(t, "first", 24)
+	if diags := resourceServerCreate(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	err := connector.Update(ctx, meta.newConnector("10.116.0.0/14"), func(networkConfig *connector.NetworkConfig) error {
+		delete(networkConfig.Subnets, "first")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := resourceServerRead(ctx, first, meta); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if first.Id() != "" {
+		t.Fatal("A released reservation should be removed from the state")
+	}
+}
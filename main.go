@@ -25,7 +25,6 @@ var (
 	// commit  string = ""
 )
 
-// TODO: Implement terraform import!!!
 func main() {
 	var debugMode bool
 